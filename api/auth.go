@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+
+	"github.com/sailhouse/sailhouse/models"
+)
+
+// ExchangeAuthCode swaps a login code for a user token once the code has
+// been approved in the dashboard. It fails with ErrNotFound until then.
+func (c *SailhouseClient) ExchangeAuthCode(ctx context.Context, code string) (string, error) {
+	var response struct {
+		Token string `json:"token"`
+	}
+
+	err := c.req().
+		Path("/user/auth/token").
+		Param("code", code).
+		ToJSON(&response).
+		Fetch(ctx)
+
+	return response.Token, err
+}
+
+// GetCurrentUser returns the user the client's token belongs to.
+func (c *SailhouseClient) GetCurrentUser(ctx context.Context) (models.User, error) {
+	var user models.User
+	err := c.req().
+		Path("/user").
+		ToJSON(&user).
+		Fetch(ctx)
+
+	return user, err
+}
+
+// RevokeCurrentToken revokes the client's own token, it can't be used
+// afterwards.
+func (c *SailhouseClient) RevokeCurrentToken(ctx context.Context) error {
+	return c.req().
+		Path("/user/auth/token").
+		Method("DELETE").
+		Fetch(ctx)
+}
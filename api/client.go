@@ -0,0 +1,145 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/carlmjohnson/requests"
+)
+
+const (
+	DefaultBaseURL   = "https://api.sailhouse.dev"
+	DefaultUserAgent = "sailhouse-go"
+)
+
+// SailhouseClient talks to the Sailhouse API. Create one with
+// NewSailhouseClient; it's safe for concurrent use.
+type SailhouseClient struct {
+	token     string
+	team      string
+	baseURL   string
+	userAgent string
+
+	httpClient *http.Client
+	logger     *slog.Logger
+	retry      RetryPolicy
+	timeout    time.Duration
+	transport  http.RoundTripper
+}
+
+type ClientOption func(*SailhouseClient)
+
+// WithTeam sets the team every app-scoped request is made against.
+func WithTeam(team string) ClientOption {
+	return func(c *SailhouseClient) {
+		c.team = team
+	}
+}
+
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *SailhouseClient) {
+		if baseURL != "" {
+			c.baseURL = baseURL
+		}
+	}
+}
+
+// WithHTTPClient sends requests through the given client. Its transport is
+// wrapped with the client's retry and timeout handling.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *SailhouseClient) {
+		c.httpClient = httpClient
+	}
+}
+
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *SailhouseClient) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithLogger logs each request at debug level and retries at info level.
+func WithLogger(logger *slog.Logger) ClientOption {
+	return func(c *SailhouseClient) {
+		c.logger = logger
+	}
+}
+
+func WithRetry(policy RetryPolicy) ClientOption {
+	return func(c *SailhouseClient) {
+		c.retry = policy
+	}
+}
+
+// WithTimeout bounds each attempt of a request, zero disables the timeout.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *SailhouseClient) {
+		c.timeout = timeout
+	}
+}
+
+func NewSailhouseClient(token string, opts ...ClientOption) *SailhouseClient {
+	c := &SailhouseClient{
+		token:      token,
+		baseURL:    DefaultBaseURL,
+		userAgent:  DefaultUserAgent,
+		httpClient: http.DefaultClient,
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		retry:      DefaultRetryPolicy(),
+		timeout:    DefaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	base := c.httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	c.transport = &retryTransport{
+		base:    networkTransport(base),
+		policy:  c.retry,
+		timeout: c.timeout,
+		logger:  c.logger,
+	}
+
+	return c
+}
+
+// Team is the team requests are made against.
+func (c *SailhouseClient) Team() string {
+	return c.team
+}
+
+func (c *SailhouseClient) req() *requests.Builder {
+	return requests.
+		URL(c.baseURL).
+		Client(c.httpClient).
+		Transport(c.transport).
+		Header("Authorization", c.token).
+		UserAgent(c.userAgent).
+		AddValidator(checkResponse)
+}
+
+// decodeIfPresent decodes a JSON response into v, leaving v as it is when
+// the API doesn't send a body.
+func decodeIfPresent(v any) requests.ResponseHandler {
+	return func(res *http.Response) error {
+		data, err := io.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+
+		if len(bytes.TrimSpace(data)) == 0 {
+			return nil
+		}
+
+		return json.Unmarshal(data, v)
+	}
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/carlmjohnson/requests"
+)
+
+type ErrorCode string
+
+const (
+	CodeNotFound     ErrorCode = "not_found"
+	CodeConflict     ErrorCode = "conflict"
+	CodeUnauthorized ErrorCode = "unauthorized"
+	CodeRateLimited  ErrorCode = "rate_limited"
+	CodeValidation   ErrorCode = "validation"
+	CodeNetwork      ErrorCode = "network"
+	CodeServer       ErrorCode = "server"
+	CodeUnknown      ErrorCode = "unknown"
+)
+
+// Sentinels for use with errors.Is, which matches any *Error with the same
+// code.
+var (
+	ErrNotFound     = &Error{Code: CodeNotFound}
+	ErrConflict     = &Error{Code: CodeConflict}
+	ErrUnauthorized = &Error{Code: CodeUnauthorized}
+	ErrRateLimited  = &Error{Code: CodeRateLimited}
+	ErrValidation   = &Error{Code: CodeValidation}
+	ErrNetwork      = &Error{Code: CodeNetwork}
+	ErrServer       = &Error{Code: CodeServer}
+)
+
+// Error is returned for every failed API call. Errors from the HTTP layer
+// wrap a *requests.ResponseError, network failures wrap the transport error.
+type Error struct {
+	Code       ErrorCode
+	StatusCode int
+	// Message is the API's own explanation when it sent one.
+	Message string
+	Details json.RawMessage
+	// RetryAfter is set from the Retry-After header on rate limited and
+	// unavailable responses.
+	RetryAfter time.Duration
+
+	Err error
+}
+
+func (e *Error) Error() string {
+	description := map[ErrorCode]string{
+		CodeNotFound:     "not found",
+		CodeConflict:     "already exists or conflicts with another resource",
+		CodeUnauthorized: "not authorized",
+		CodeRateLimited:  "rate limited",
+		CodeValidation:   "invalid request",
+		CodeNetwork:      "could not reach the Sailhouse API",
+		CodeServer:       "the Sailhouse API had an internal error",
+	}[e.Code]
+	if description == "" {
+		description = "unexpected response"
+	}
+
+	if e.Message != "" {
+		description = fmt.Sprintf("%s: %s", description, e.Message)
+	} else if e.Code == CodeNetwork && errors.Is(e.Err, context.DeadlineExceeded) {
+		description = fmt.Sprintf("%s: request timed out", description)
+	} else if e.Code == CodeNetwork && e.Err != nil {
+		description = fmt.Sprintf("%s: %s", description, e.Err)
+	}
+
+	if e.StatusCode != 0 {
+		description = fmt.Sprintf("%s (%d)", description, e.StatusCode)
+	}
+
+	return description
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// ErrorCode and ErrorDetails let the CLI's output report the error without
+// depending on this package.
+func (e *Error) ErrorCode() string {
+	return string(e.Code)
+}
+
+func (e *Error) ErrorDetails() any {
+	if len(e.Details) == 0 {
+		return nil
+	}
+
+	return e.Details
+}
+
+func codeForStatus(status int) ErrorCode {
+	switch {
+	case status == http.StatusNotFound:
+		return CodeNotFound
+	case status == http.StatusConflict:
+		return CodeConflict
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return CodeUnauthorized
+	case status == http.StatusTooManyRequests:
+		return CodeRateLimited
+	case status == http.StatusBadRequest || status == http.StatusUnprocessableEntity:
+		return CodeValidation
+	case status >= 500:
+		return CodeServer
+	default:
+		return CodeUnknown
+	}
+}
+
+// checkResponse is the response validator for every API request. It accepts
+// any 2xx status and turns anything else into an *Error, keeping the API's
+// error message when the body has one.
+func checkResponse(res *http.Response) error {
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+
+	apiErr := &Error{
+		Code:       codeForStatus(res.StatusCode),
+		StatusCode: res.StatusCode,
+		RetryAfter: parseRetryAfter(res.Header.Get("Retry-After")),
+		Err:        fmt.Errorf("%w: unexpected status: %d", (*requests.ResponseError)(res), res.StatusCode),
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+
+	var payload struct {
+		Message string          `json:"message"`
+		Error   string          `json:"error"`
+		Details json.RawMessage `json:"details"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		apiErr.Message = payload.Message
+		if apiErr.Message == "" {
+			apiErr.Message = payload.Error
+		}
+		apiErr.Details = payload.Details
+	}
+
+	return apiErr
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+
+	return 0
+}
+
+// networkTransport marks failures to reach the API as network errors. Requests
+// cancelled by the caller are left alone.
+func networkTransport(base http.RoundTripper) http.RoundTripper {
+	return requests.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		res, err := base.RoundTrip(req)
+		if err != nil && !errors.Is(req.Context().Err(), context.Canceled) {
+			return nil, &Error{Code: CodeNetwork, Err: err}
+		}
+
+		return res, err
+	})
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// NextCursorHeader is set on list responses that have more items. Its value
+// is passed back as the cursor parameter to fetch the next page.
+const NextCursorHeader = "X-Next-Cursor"
+
+type ListOptions struct {
+	// PageSize is the number of items fetched per request, zero uses the
+	// API's default.
+	PageSize int
+}
+
+// Pager lists a collection a page at a time. Create one with one of the
+// client's List methods; a Pager isn't safe for concurrent use.
+type Pager[T any] struct {
+	client   *SailhouseClient
+	path     string
+	pageSize int
+
+	cursor string
+	done   bool
+}
+
+func newPager[T any](c *SailhouseClient, path string, opts ListOptions) *Pager[T] {
+	return &Pager[T]{client: c, path: path, pageSize: opts.PageSize}
+}
+
+// HasMore reports whether NextPage may return more items. The last page can
+// be empty when the collection ends on a page boundary.
+func (p *Pager[T]) HasMore() bool {
+	return !p.done
+}
+
+// NextPage fetches the next page, returning nil once the collection runs
+// out.
+func (p *Pager[T]) NextPage(ctx context.Context) ([]T, error) {
+	if p.done {
+		return nil, nil
+	}
+
+	page := []T{}
+	header := http.Header{}
+
+	req := p.client.req().
+		Path(p.path).
+		CopyHeaders(header)
+
+	if p.pageSize > 0 {
+		req = req.Param("limit", strconv.Itoa(p.pageSize))
+	}
+
+	if p.cursor != "" {
+		req = req.Param("cursor", p.cursor)
+	}
+
+	err := req.
+		ToJSON(&page).
+		Fetch(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	next := header.Get(NextCursorHeader)
+	if next != "" && next == p.cursor {
+		p.done = true
+		return nil, fmt.Errorf("listing %s: the API returned the same cursor twice", p.path)
+	}
+
+	p.cursor = next
+	p.done = next == ""
+
+	return page, nil
+}
+
+// Each calls yield with every item in the collection, fetching pages as
+// needed, until yield returns false or the collection runs out.
+func (p *Pager[T]) Each(ctx context.Context, yield func(T) bool) error {
+	for p.HasMore() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range page {
+			if !yield(item) {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// All fetches every remaining item in the collection.
+func (p *Pager[T]) All(ctx context.Context) ([]T, error) {
+	items := []T{}
+
+	err := p.Each(ctx, func(item T) bool {
+		items = append(items, item)
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
@@ -0,0 +1,101 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sailhouse/sailhouse/api"
+	"github.com/sailhouse/sailhouse/emulator"
+	"github.com/sailhouse/sailhouse/models"
+)
+
+func TestPagerPages(t *testing.T) {
+	ctx := context.Background()
+	srv := emulator.NewTestServer(t)
+	client := api.NewSailhouseClient("token", api.WithTeam("team"), api.WithBaseURL(srv.URL))
+
+	for i := 0; i < 25; i++ {
+		if _, err := client.CreateTopic(ctx, "app", api.CreateTopic{Slug: fmt.Sprintf("topic-%02d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pager := client.ListTopics("app", api.ListOptions{PageSize: 10})
+
+	sizes := []int{}
+	seen := map[string]bool{}
+	for pager.HasMore() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sizes = append(sizes, len(page))
+		for _, topic := range page {
+			if seen[topic.Slug] {
+				t.Errorf("%s listed twice", topic.Slug)
+			}
+			seen[topic.Slug] = true
+		}
+	}
+
+	if fmt.Sprint(sizes) != "[10 10 5]" {
+		t.Errorf("page sizes = %v, want [10 10 5]", sizes)
+	}
+
+	if len(seen) != 25 {
+		t.Errorf("listed %d topics, want 25", len(seen))
+	}
+
+	page, err := pager.NextPage(ctx)
+	if err != nil || page != nil {
+		t.Errorf("NextPage after the end = %v, %v, want nil, nil", page, err)
+	}
+}
+
+func TestPagerEachStopsEarly(t *testing.T) {
+	ctx := context.Background()
+	srv := emulator.NewTestServer(t)
+	client := api.NewSailhouseClient("token", api.WithTeam("team"), api.WithBaseURL(srv.URL))
+
+	for i := 0; i < 5; i++ {
+		if _, err := client.CreateTopic(ctx, "app", api.CreateTopic{Slug: fmt.Sprintf("topic-%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pager := client.ListTopics("app", api.ListOptions{PageSize: 2})
+
+	count := 0
+	err := pager.Each(ctx, func(models.Topic) bool {
+		count++
+		return count < 3
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 3 {
+		t.Errorf("yielded %d topics, want 3", count)
+	}
+}
+
+func TestPagerRepeatedCursor(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(api.NextCursorHeader, "same")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":"1","slug":"acme"}]`))
+	}))
+	defer srv.Close()
+
+	client := api.NewSailhouseClient("token", api.WithBaseURL(srv.URL))
+
+	_, err := client.GetTeams(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "same cursor twice") {
+		t.Fatalf("err = %v, want a repeated cursor error", err)
+	}
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried. Network errors,
+// 429s and 502/503/504s are retried for idempotent methods. POSTs and PATCHes
+// are only retried when they carry an Idempotency-Key. A Retry-After longer
+// than MaxBackoff isn't waited for.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt, zero
+	// disables retries.
+	MaxRetries int
+	// MinBackoff is the delay before the first retry, doubling with each
+	// retry up to MaxBackoff. Delays are jittered.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 3,
+		MinBackoff: 500 * time.Millisecond,
+		MaxBackoff: 30 * time.Second,
+	}
+}
+
+// DefaultTimeout bounds each attempt of a request.
+const DefaultTimeout = 30 * time.Second
+
+// backoff is the delay before the given retry, counting from zero.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.MinBackoff << retry
+	if delay <= 0 || delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+
+	// Equal jitter: somewhere between half and all of the delay, so
+	// clients that failed together don't retry together.
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+func shouldRetry(req *http.Request, res *http.Response, err error) bool {
+	if err != nil {
+		// Cancelled by the caller rather than timed out or failed.
+		if errors.Is(req.Context().Err(), context.Canceled) {
+			return false
+		}
+		return isIdempotent(req)
+	}
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return isIdempotent(req)
+	}
+
+	return false
+}
+
+type timeoutExtensionKey struct{}
+
+// withTimeoutExtension lengthens the per-attempt timeout for requests the
+// server is expected to hold open, like long-polling pulls.
+func withTimeoutExtension(ctx context.Context, extra time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutExtensionKey{}, extra)
+}
+
+// retryTransport applies the client's timeout to each attempt and retries
+// according to its policy.
+type retryTransport struct {
+	base    http.RoundTripper
+	policy  RetryPolicy
+	timeout time.Duration
+	logger  *slog.Logger
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for retry := 0; ; retry++ {
+		attempt := req
+		if retry > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attempt = req.Clone(req.Context())
+			attempt.Body = body
+		}
+
+		start := time.Now()
+		res, err := t.roundTrip(attempt)
+
+		status := 0
+		if res != nil {
+			status = res.StatusCode
+		}
+		t.logger.Debug("sailhouse request", "method", req.Method, "url", req.URL.Redacted(), "status", status, "duration", time.Since(start), "error", err)
+
+		if retry >= t.policy.MaxRetries || !shouldRetry(req, res, err) {
+			return res, err
+		}
+
+		delay := t.policy.backoff(retry)
+		if res != nil {
+			if retryAfter := parseRetryAfter(res.Header.Get("Retry-After")); retryAfter > 0 {
+				// Waiting longer than the policy allows would look like a
+				// hang, so give up with the response instead.
+				if retryAfter > t.policy.MaxBackoff {
+					return res, err
+				}
+				delay = retryAfter
+			}
+			io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+			res.Body.Close()
+		}
+
+		t.logger.Info("retrying sailhouse request", "method", req.Method, "url", req.URL.Redacted(), "status", status, "error", err, "retry", retry+1, "delay", delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (t *retryTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	timeout := t.timeout
+	if extra, ok := req.Context().Value(timeoutExtensionKey{}).(time.Duration); ok {
+		timeout += extra
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	res, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The timeout covers reading the body too, so it's only released once
+	// the body is closed.
+	res.Body = &cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
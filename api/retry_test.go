@@ -0,0 +1,300 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	tests := []struct {
+		retry    int
+		min, max time.Duration
+	}{
+		{0, 50 * time.Millisecond, 100 * time.Millisecond},
+		{1, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond, 800 * time.Millisecond},
+		// Capped at MaxBackoff, including when the shift overflows.
+		{4, 500 * time.Millisecond, time.Second},
+		{70, 500 * time.Millisecond, time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := policy.backoff(tt.retry); got < tt.min || got > tt.max {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", tt.retry, got, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		key    bool
+		status int
+		err    error
+		want   bool
+	}{
+		{"GET 503", http.MethodGet, false, http.StatusServiceUnavailable, nil, true},
+		{"GET 429", http.MethodGet, false, http.StatusTooManyRequests, nil, true},
+		{"GET 404", http.MethodGet, false, http.StatusNotFound, nil, false},
+		{"GET 500", http.MethodGet, false, http.StatusInternalServerError, nil, false},
+		{"GET network error", http.MethodGet, false, 0, errors.New("connection reset"), true},
+		{"DELETE 502", http.MethodDelete, false, http.StatusBadGateway, nil, true},
+		{"POST 503", http.MethodPost, false, http.StatusServiceUnavailable, nil, false},
+		{"POST 429", http.MethodPost, false, http.StatusTooManyRequests, nil, false},
+		{"POST network error", http.MethodPost, false, 0, errors.New("connection reset"), false},
+		{"POST with key 503", http.MethodPost, true, http.StatusServiceUnavailable, nil, true},
+		{"POST with key 429", http.MethodPost, true, http.StatusTooManyRequests, nil, true},
+		{"PATCH with key network error", http.MethodPatch, true, 0, errors.New("connection reset"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.key {
+				req.Header.Set("Idempotency-Key", "key")
+			}
+
+			var res *http.Response
+			if tt.err == nil {
+				res = &http.Response{StatusCode: tt.status}
+			}
+
+			if got := shouldRetry(req, res, tt.err); got != tt.want {
+				t.Errorf("shouldRetry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldRetryCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	if shouldRetry(req, nil, context.Canceled) {
+		t.Error("cancelled requests shouldn't be retried")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("3"); got != 3*time.Second {
+		t.Errorf("parseRetryAfter(3) = %s, want 3s", got)
+	}
+
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got < 58*time.Second || got > time.Minute {
+		t.Errorf("parseRetryAfter(%q) = %s, want about 1m", date, got)
+	}
+
+	for _, value := range []string{"", "soon"} {
+		if got := parseRetryAfter(value); got != 0 {
+			t.Errorf("parseRetryAfter(%q) = %s, want 0", value, got)
+		}
+	}
+}
+
+// flakyServer fails the first failures requests with status, then answers
+// with body. It records the Idempotency-Key and body of every request.
+type flakyServer struct {
+	failures   int
+	status     int
+	retryAfter string
+	body       string
+
+	mu       sync.Mutex
+	attempts int
+	keys     []string
+	bodies   []string
+}
+
+func (s *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts++
+	s.keys = append(s.keys, r.Header.Get("Idempotency-Key"))
+	s.bodies = append(s.bodies, string(body))
+
+	if s.attempts <= s.failures {
+		if s.retryAfter != "" {
+			w.Header().Set("Retry-After", s.retryAfter)
+		}
+		w.WriteHeader(s.status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, s.body)
+}
+
+func newTestClient(url string, retries int) *SailhouseClient {
+	return NewSailhouseClient("token",
+		WithTeam("team"),
+		WithBaseURL(url),
+		WithRetry(RetryPolicy{MaxRetries: retries, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}),
+	)
+}
+
+func TestRetryRecovers(t *testing.T) {
+	flaky := &flakyServer{failures: 2, status: http.StatusServiceUnavailable, body: `[{"id":"1","slug":"acme"}]`}
+	srv := httptest.NewServer(flaky)
+	defer srv.Close()
+
+	teams, err := newTestClient(srv.URL, 3).GetTeams(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(teams) != 1 || teams[0].Slug != "acme" {
+		t.Errorf("teams = %+v, want acme", teams)
+	}
+
+	if flaky.attempts != 3 {
+		t.Errorf("attempts = %d, want 3", flaky.attempts)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	flaky := &flakyServer{failures: 10, status: http.StatusBadGateway}
+	srv := httptest.NewServer(flaky)
+	defer srv.Close()
+
+	_, err := newTestClient(srv.URL, 2).GetTeams(context.Background())
+	if !errors.Is(err, ErrServer) {
+		t.Fatalf("err = %v, want a server error", err)
+	}
+
+	if flaky.attempts != 3 {
+		t.Errorf("attempts = %d, want 3", flaky.attempts)
+	}
+}
+
+func TestRetryHonoursRetryAfter(t *testing.T) {
+	flaky := &flakyServer{failures: 1, status: http.StatusTooManyRequests, retryAfter: "1", body: `[]`}
+	srv := httptest.NewServer(flaky)
+	defer srv.Close()
+
+	client := NewSailhouseClient("token",
+		WithBaseURL(srv.URL),
+		WithRetry(RetryPolicy{MaxRetries: 1, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Second}),
+	)
+
+	start := time.Now()
+	if _, err := client.GetTeams(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The policy's own first backoff is at most 1ms.
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %s, want at least the 1s Retry-After", elapsed)
+	}
+}
+
+func TestRetryGivesUpOnLongRetryAfter(t *testing.T) {
+	for _, retryAfter := range []string{"86400", time.Now().Add(24 * time.Hour).UTC().Format(http.TimeFormat)} {
+		flaky := &flakyServer{failures: 1, status: http.StatusTooManyRequests, retryAfter: retryAfter, body: `[]`}
+		srv := httptest.NewServer(flaky)
+
+		start := time.Now()
+		_, err := newTestClient(srv.URL, 3).GetTeams(context.Background())
+		srv.Close()
+
+		if !errors.Is(err, ErrRateLimited) {
+			t.Errorf("Retry-After %s: err = %v, want rate limited", retryAfter, err)
+		}
+
+		if flaky.attempts != 1 {
+			t.Errorf("Retry-After %s: attempts = %d, want 1", retryAfter, flaky.attempts)
+		}
+
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Retry-After %s: gave up after %s, want immediately", retryAfter, elapsed)
+		}
+	}
+}
+
+func TestPublishRetriesWithKey(t *testing.T) {
+	flaky := &flakyServer{failures: 1, status: http.StatusServiceUnavailable, body: `{"id":"event"}`}
+	srv := httptest.NewServer(flaky)
+	defer srv.Close()
+
+	_, err := newTestClient(srv.URL, 2).PublishEvent(context.Background(), "app", PublishEvent{
+		TopicSlug:      "orders",
+		Data:           json.RawMessage(`{"id":42}`),
+		IdempotencyKey: "key",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if flaky.attempts != 2 {
+		t.Fatalf("attempts = %d, want 2", flaky.attempts)
+	}
+
+	if flaky.keys[0] != "key" || flaky.keys[1] != "key" {
+		t.Errorf("Idempotency-Key = %q then %q, want key both times", flaky.keys[0], flaky.keys[1])
+	}
+
+	if flaky.bodies[0] != flaky.bodies[1] {
+		t.Errorf("retried body %q, want %q", flaky.bodies[1], flaky.bodies[0])
+	}
+}
+
+func TestPublishRetriesWithGeneratedKey(t *testing.T) {
+	flaky := &flakyServer{failures: 1, status: http.StatusServiceUnavailable, body: `{"id":"event"}`}
+	srv := httptest.NewServer(flaky)
+	defer srv.Close()
+
+	resp, err := newTestClient(srv.URL, 2).PublishEvent(context.Background(), "app", PublishEvent{
+		TopicSlug: "orders",
+		Data:      json.RawMessage(`{"id":42}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.ID != "event" {
+		t.Errorf("ID = %q, want event", resp.ID)
+	}
+
+	if flaky.attempts != 2 {
+		t.Fatalf("attempts = %d, want 2", flaky.attempts)
+	}
+
+	if flaky.keys[0] == "" || flaky.keys[0] != flaky.keys[1] {
+		t.Errorf("Idempotency-Key = %q then %q, want the same generated key", flaky.keys[0], flaky.keys[1])
+	}
+
+	if flaky.bodies[0] != flaky.bodies[1] {
+		t.Errorf("retried body %q, want %q", flaky.bodies[1], flaky.bodies[0])
+	}
+}
+
+func TestPostWithoutKeyNotRetried(t *testing.T) {
+	flaky := &flakyServer{failures: 1, status: http.StatusServiceUnavailable, body: `{}`}
+	srv := httptest.NewServer(flaky)
+	defer srv.Close()
+
+	_, err := newTestClient(srv.URL, 3).CreateApp(context.Background(), CreateApp{Slug: "orders"})
+	if !errors.Is(err, ErrServer) {
+		t.Fatalf("err = %v, want a server error", err)
+	}
+
+	if flaky.attempts != 1 {
+		t.Errorf("attempts = %d, want 1", flaky.attempts)
+	}
+}
@@ -2,212 +2,488 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"time"
 
-	"github.com/carlmjohnson/requests"
 	"github.com/sailhouse/sailhouse/models"
-	"github.com/spf13/viper"
+	"github.com/sailhouse/sailhouse/publicid"
 )
 
-type SailhouseClient struct {
-	token string
-	team  string
+func (c *SailhouseClient) ListTeams(opts ListOptions) *Pager[models.Team] {
+	return newPager[models.Team](c, "/teams", opts)
 }
 
-func NewSailhouseClient(token string) *SailhouseClient {
-	team := viper.GetString("team")
-	return &SailhouseClient{token, team}
+func (c *SailhouseClient) GetTeams(ctx context.Context) ([]models.Team, error) {
+	return c.ListTeams(ListOptions{}).All(ctx)
 }
 
-func (c *SailhouseClient) req() *requests.Builder {
-	return requests.
-		URL("https://api.sailhouse.dev").
-		Header("Authorization", c.token)
+type CreateApp struct {
+	Slug string `json:"slug"`
+	// Name defaults to the slug.
+	Name string `json:"name,omitempty"`
 }
 
-func (c *SailhouseClient) GetTeams(ctx context.Context) ([]models.Team, error) {
-	teams := []models.Team{}
+func (c *SailhouseClient) CreateApp(ctx context.Context, newApp CreateApp) (models.App, error) {
+	if newApp.Name == "" {
+		newApp.Name = newApp.Slug
+	}
 
+	var app models.App
 	err := c.req().
-		Path("teams").
-		ToJSON(&teams).
+		Pathf("/teams/%s/apps/%s", c.team, newApp.Slug).
+		BodyJSON(newApp).
+		ToJSON(&app).
 		Fetch(ctx)
 
-	return teams, err
+	return app, err
 }
 
-func (c *SailhouseClient) CreateApp(ctx context.Context, name string) error {
-	return c.req().
-		Pathf("/teams/%s/apps/%s", c.team, name).
-		BodyJSON(map[string]string{"name": name, "slug": name}).
-		Fetch(ctx)
+func (c *SailhouseClient) ListApps(opts ListOptions) *Pager[models.App] {
+	return newPager[models.App](c, fmt.Sprintf("/teams/%s/apps", c.team), opts)
 }
 
 func (c *SailhouseClient) GetApps(ctx context.Context) ([]models.App, error) {
-	apps := []models.App{}
+	return c.ListApps(ListOptions{}).All(ctx)
+}
+
+func (c *SailhouseClient) GetApp(ctx context.Context, appID string) (*models.App, error) {
+	app := models.App{}
 
 	err := c.req().
-		Pathf("/teams/%s/apps", c.team).
-		ToJSON(&apps).
+		Pathf("/teams/%s/apps/%s", c.team, appID).
+		ToJSON(&app).
 		Fetch(ctx)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return apps, nil
+	return &app, nil
+}
+
+// UpdateApp changes only the fields that are set.
+type UpdateApp struct {
+	Slug *string `json:"slug,omitempty"`
+}
+
+func (c *SailhouseClient) UpdateApp(ctx context.Context, appID string, update UpdateApp) (models.App, error) {
+	var app models.App
+	err := c.req().
+		Pathf("/teams/%s/apps/%s", c.team, appID).
+		Method("PATCH").
+		BodyJSON(update).
+		ToJSON(&app).
+		Fetch(ctx)
+
+	return app, err
+}
+
+// DeleteApp deletes the app along with its topics, subscriptions and tokens.
+func (c *SailhouseClient) DeleteApp(ctx context.Context, appID string) error {
+	return c.req().
+		Pathf("/teams/%s/apps/%s", c.team, appID).
+		Method("DELETE").
+		Fetch(ctx)
+}
+
+func (c *SailhouseClient) ListTopics(appID string, opts ListOptions) *Pager[models.Topic] {
+	return newPager[models.Topic](c, fmt.Sprintf("/teams/%s/apps/%s/topics", c.team, appID), opts)
 }
 
 func (c *SailhouseClient) GetTopics(ctx context.Context, appID string) ([]models.Topic, error) {
-	topics := []models.Topic{}
+	return c.ListTopics(appID, ListOptions{}).All(ctx)
+}
 
+func (c *SailhouseClient) GetAppUsage(ctx context.Context, appID string) (models.AppUsage, error) {
+	var usage models.AppUsage
 	err := c.req().
-		Pathf("/teams/%s/apps/%s/topics", c.team, appID).
-		ToJSON(&topics).
+		Pathf("/teams/%s/apps/%s/usage", c.team, appID).
+		ToJSON(&usage).
 		Fetch(ctx)
 
-	if err != nil {
-		return nil, err
-	}
+	return usage, err
+}
+
+// Token scopes limit what an app token can be used for.
+const (
+	TokenScopeFull    = "full"
+	TokenScopePublish = "publish"
+	TokenScopeRead    = "read"
+)
 
-	return topics, nil
+type CreateToken struct {
+	Name string `json:"name,omitempty"`
+	// Scope defaults to TokenScopeFull.
+	Scope string `json:"scope,omitempty"`
+	// ExpiresAt is when the token stops working, nil never expires.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
+// CreateTokenResponse holds the token itself, which can't be fetched again,
+// alongside its preview.
 type CreateTokenResponse struct {
+	models.TokenPreview
 	Token string `json:"token"`
 }
 
-func (c *SailhouseClient) CreateToken(ctx context.Context, appID string) (string, error) {
+func (c *SailhouseClient) CreateToken(ctx context.Context, appID string, newToken CreateToken) (CreateTokenResponse, error) {
 	var resp CreateTokenResponse
 
 	err := c.req().
 		Pathf("/teams/%s/apps/%s/tokens", c.team, appID).
-		Method("POST").
+		BodyJSON(newToken).
 		ToJSON(&resp).
 		Fetch(ctx)
 
-	if err != nil {
-		return "", err
-	}
+	return resp, err
+}
+
+// UpdateToken changes only the fields that are set.
+type UpdateToken struct {
+	Name      *string    `json:"name,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (c *SailhouseClient) UpdateToken(ctx context.Context, appID, tokenID string, update UpdateToken) (models.TokenPreview, error) {
+	var token models.TokenPreview
+	err := c.req().
+		Pathf("/teams/%s/apps/%s/tokens/%s", c.team, appID, tokenID).
+		Method("PATCH").
+		BodyJSON(update).
+		ToJSON(&token).
+		Fetch(ctx)
+
+	return token, err
+}
 
-	return resp.Token, nil
+func (c *SailhouseClient) ListTokens(appID string, opts ListOptions) *Pager[models.TokenPreview] {
+	return newPager[models.TokenPreview](c, fmt.Sprintf("/teams/%s/apps/%s/tokens", c.team, appID), opts)
 }
 
 func (c *SailhouseClient) GetTokens(ctx context.Context, appID string) ([]models.TokenPreview, error) {
-	var resp []models.TokenPreview
+	return c.ListTokens(appID, ListOptions{}).All(ctx)
+}
+
+// DeleteToken revokes the token immediately.
+func (c *SailhouseClient) DeleteToken(ctx context.Context, appID, tokenID string) error {
+	return c.req().
+		Pathf("/teams/%s/apps/%s/tokens/%s", c.team, appID, tokenID).
+		Method("DELETE").
+		Fetch(ctx)
+}
+
+func (c *SailhouseClient) GetTopic(ctx context.Context, appID, slug string) (*models.Topic, error) {
+	topic := models.Topic{}
 
 	err := c.req().
-		Pathf("/teams/%s/apps/%s/tokens", c.team, appID).
-		ToJSON(&resp).
+		Pathf("/teams/%s/apps/%s/topics/%s", c.team, appID, slug).
+		ToJSON(&topic).
 		Fetch(ctx)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return resp, nil
+	return &topic, nil
 }
 
-func (c *SailhouseClient) CreateTopic(ctx context.Context, appID, slug string) error {
-	return c.req().
-		Pathf("/teams/%s/apps/%s/topics", c.team, appID).
-		BodyJSON(map[string]any{"slug": slug, "subscriptions": []string{}}).
+type CreateTopic struct {
+	Slug string `json:"slug"`
+	// SchemaKey marks the topic as managed by the schema file with that key.
+	SchemaKey string `json:"schema_key,omitempty"`
+}
+
+func (c *SailhouseClient) CreateTopic(ctx context.Context, appID string, newTopic CreateTopic) (models.Topic, error) {
+	body := struct {
+		CreateTopic
+		Subscriptions []string `json:"subscriptions"`
+	}{newTopic, []string{}}
+
+	// Topics with a schema key are created at their own path.
+	path := fmt.Sprintf("/teams/%s/apps/%s/topics", c.team, appID)
+	if newTopic.SchemaKey != "" {
+		path = fmt.Sprintf("/teams/%s/apps/%s/topics/%s", c.team, appID, newTopic.Slug)
+	}
+
+	topic := models.Topic{Slug: newTopic.Slug, SchemaKey: newTopic.SchemaKey}
+	err := c.req().
+		Path(path).
+		BodyJSON(body).
+		Handle(decodeIfPresent(&topic)).
 		Fetch(ctx)
+
+	return topic, err
 }
 
-func (c *SailhouseClient) DeleteTopic(ctx context.Context, appID, slug string) error {
-	return c.req().
+// UpdateTopic changes only the fields that are set. An empty SchemaKey
+// releases the topic from its schema.
+type UpdateTopic struct {
+	Slug      *string `json:"slug,omitempty"`
+	SchemaKey *string `json:"schema_key,omitempty"`
+}
+
+func (c *SailhouseClient) UpdateTopic(ctx context.Context, appID, slug string, update UpdateTopic) (models.Topic, error) {
+	var topic models.Topic
+	err := c.req().
 		Pathf("/teams/%s/apps/%s/topics/%s", c.team, appID, slug).
-		Method("DELETE").
+		Method("PATCH").
+		BodyJSON(update).
+		ToJSON(&topic).
 		Fetch(ctx)
+
+	return topic, err
 }
 
-func (c *SailhouseClient) CreateTopicWithKey(ctx context.Context, appID, slug, key string) error {
+// DeleteTopic deletes the topic along with its subscriptions.
+func (c *SailhouseClient) DeleteTopic(ctx context.Context, appID, slug string) error {
 	return c.req().
 		Pathf("/teams/%s/apps/%s/topics/%s", c.team, appID, slug).
-		BodyJSON(map[string]any{"slug": slug, "subscriptions": []string{}, "schema_key": key}).
+		Method("DELETE").
 		Fetch(ctx)
 }
 
+func (c *SailhouseClient) ListSubscriptions(appID, topicSlug string, opts ListOptions) *Pager[models.Subscription] {
+	return newPager[models.Subscription](c, fmt.Sprintf("/teams/%s/apps/%s/topics/%s/subscriptions", c.team, appID, topicSlug), opts)
+}
+
 func (c *SailhouseClient) GetSubscriptions(ctx context.Context, appID, topicSlug string) ([]models.Subscription, error) {
-	subscriptions := []models.Subscription{}
+	return c.ListSubscriptions(appID, topicSlug, ListOptions{}).All(ctx)
+}
+
+func (c *SailhouseClient) GetSubscription(ctx context.Context, appID, topicSlug, subscriptionSlug string) (*models.Subscription, error) {
+	subscription := models.Subscription{}
 
 	err := c.req().
-		Pathf("/teams/%s/apps/%s/topics/%s/subscriptions", c.team, appID, topicSlug).
-		ToJSON(&subscriptions).
+		Pathf("/teams/%s/apps/%s/topics/%s/subscriptions/%s", c.team, appID, topicSlug, subscriptionSlug).
+		ToJSON(&subscription).
 		Fetch(ctx)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return subscriptions, nil
+	return &subscription, nil
 }
 
-func (c *SailhouseClient) GetSubscription(ctx context.Context, appID, topicSlug, subscriptionSlug string) (*models.Subscription, error) {
-	subscription := models.Subscription{}
+type CreateSubscription struct {
+	Slug        string `json:"slug"`
+	TopicSlug   string `json:"-"`
+	Type        string `json:"type"`
+	Endpoint    string `json:"endpoint,omitempty"`
+	SchemaKey   string `json:"schema_key,omitempty"`
+	FilterPath  string `json:"filter_path,omitempty"`
+	FilterValue string `json:"filter_value,omitempty"`
+}
 
+func (c *SailhouseClient) CreateSubscription(ctx context.Context, appID string, newSub CreateSubscription) (models.Subscription, error) {
+	var sub models.Subscription
+	err := c.req().
+		Pathf("/teams/%s/apps/%s/topics/%s/subscriptions", c.team, appID, newSub.TopicSlug).
+		BodyJSON(newSub).
+		ToJSON(&sub).
+		Fetch(ctx)
+
+	return sub, err
+}
+
+// UpdateSubscription changes only the fields that are set. Empty filter
+// fields remove the filter. Updating a subscription in place keeps the
+// events waiting to be delivered to it.
+type UpdateSubscription struct {
+	Type        *string `json:"type,omitempty"`
+	Endpoint    *string `json:"endpoint,omitempty"`
+	SchemaKey   *string `json:"schema_key,omitempty"`
+	FilterPath  *string `json:"filter_path,omitempty"`
+	FilterValue *string `json:"filter_value,omitempty"`
+	Paused      *bool   `json:"paused,omitempty"`
+}
+
+func (c *SailhouseClient) UpdateSubscription(ctx context.Context, appID, topicSlug, subscriptionSlug string, update UpdateSubscription) (models.Subscription, error) {
+	var sub models.Subscription
 	err := c.req().
 		Pathf("/teams/%s/apps/%s/topics/%s/subscriptions/%s", c.team, appID, topicSlug, subscriptionSlug).
-		ToJSON(&subscription).
+		Method("PATCH").
+		BodyJSON(update).
+		ToJSON(&sub).
+		Fetch(ctx)
+
+	return sub, err
+}
+
+func (c *SailhouseClient) DeleteSubscription(ctx context.Context, appID, topicSlug, subscriptionSlug string) error {
+	return c.req().
+		Pathf("/teams/%s/apps/%s/topics/%s/subscriptions/%s", c.team, appID, topicSlug, subscriptionSlug).
+		Method("DELETE").
+		Fetch(ctx)
+}
+
+type PublishEvent struct {
+	TopicSlug string            `json:"-"`
+	Data      json.RawMessage   `json:"data"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	SendAt    *time.Time        `json:"send_at,omitempty"`
+	// IdempotencyKey is sent as a header rather than in the body. When
+	// empty a random key is used, so retries of the same call can't publish
+	// the event twice.
+	IdempotencyKey string `json:"-"`
+}
+
+type PublishEventResponse struct {
+	ID string `json:"id"`
+}
+
+func (c *SailhouseClient) PublishEvent(ctx context.Context, appID string, event PublishEvent) (PublishEventResponse, error) {
+	if event.SendAt != nil {
+		sendAt := event.SendAt.UTC().Truncate(time.Second)
+		event.SendAt = &sendAt
+	}
+
+	if event.IdempotencyKey == "" {
+		event.IdempotencyKey = publicid.Must()
+	}
+
+	var resp PublishEventResponse
+	err := c.req().
+		Pathf("/teams/%s/apps/%s/topics/%s/events", c.team, appID, event.TopicSlug).
+		Header("Idempotency-Key", event.IdempotencyKey).
+		BodyJSON(event).
+		ToJSON(&resp).
+		Fetch(ctx)
+
+	return resp, err
+}
+
+type PullEvents struct {
+	Limit int
+	// Wait is how long the server may hold the request open waiting for
+	// events to arrive. Zero returns immediately.
+	Wait time.Duration
+}
+
+func (c *SailhouseClient) PullEvents(ctx context.Context, appID, topicSlug, subscriptionSlug string, opts PullEvents) ([]models.Event, error) {
+	events := []models.Event{}
+
+	req := c.req().
+		Pathf("/teams/%s/apps/%s/topics/%s/subscriptions/%s/events", c.team, appID, topicSlug, subscriptionSlug)
+
+	if opts.Limit > 0 {
+		req = req.Param("limit", strconv.Itoa(opts.Limit))
+	}
+
+	if opts.Wait > 0 {
+		req = req.Param("wait", strconv.Itoa(int(opts.Wait.Seconds())))
+		ctx = withTimeoutExtension(ctx, opts.Wait)
+	}
+
+	err := req.
+		ToJSON(&events).
 		Fetch(ctx)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &subscription, nil
+	return events, nil
 }
 
-type CreateSubscription struct {
-	Slug        string
-	TopicSlug   string
-	Type        string
-	Endpoint    string
-	SchemaKey   string
-	FilterPath  string
-	FilterValue string
+func (c *SailhouseClient) AckEvent(ctx context.Context, appID, topicSlug, subscriptionSlug, eventID string) error {
+	return c.req().
+		Pathf("/teams/%s/apps/%s/topics/%s/subscriptions/%s/events/%s", c.team, appID, topicSlug, subscriptionSlug, eventID).
+		Method("POST").
+		Fetch(ctx)
 }
 
-func (c *SailhouseClient) CreateSubscription(ctx context.Context, appID string, newSub CreateSubscription) (models.Subscription, error) {
-	body := map[string]string{
-		"slug": newSub.Slug,
-		"type": newSub.Type,
+func (c *SailhouseClient) ListDeadLetters(appID, topicSlug, subscriptionSlug string, opts ListOptions) *Pager[models.DeadLetter] {
+	return newPager[models.DeadLetter](c, fmt.Sprintf("/teams/%s/apps/%s/topics/%s/subscriptions/%s/dead-letters", c.team, appID, topicSlug, subscriptionSlug), opts)
+}
+
+type GetDeadLetters struct {
+	Limit  int
+	Offset int
+}
+
+// GetDeadLetters fetches a single page of dead letters by offset, for
+// browsing. Use ListDeadLetters to page through all of them.
+func (c *SailhouseClient) GetDeadLetters(ctx context.Context, appID, topicSlug, subscriptionSlug string, opts GetDeadLetters) ([]models.DeadLetter, error) {
+	deadLetters := []models.DeadLetter{}
+
+	req := c.req().
+		Pathf("/teams/%s/apps/%s/topics/%s/subscriptions/%s/dead-letters", c.team, appID, topicSlug, subscriptionSlug)
+
+	if opts.Limit > 0 {
+		req = req.Param("limit", strconv.Itoa(opts.Limit))
 	}
 
-	if newSub.Endpoint != "" {
-		body["endpoint"] = newSub.Endpoint
+	if opts.Offset > 0 {
+		req = req.Param("offset", strconv.Itoa(opts.Offset))
 	}
 
-	if newSub.SchemaKey != "" {
-		body["schema_key"] = newSub.SchemaKey
+	err := req.
+		ToJSON(&deadLetters).
+		Fetch(ctx)
+
+	if err != nil {
+		return nil, err
 	}
 
-	if newSub.FilterPath != "" {
-		body["filter_path"] = newSub.FilterPath
-		fmt.Printf("filter path: %s\n", newSub.FilterPath)
+	return deadLetters, nil
+}
+
+func (c *SailhouseClient) GetDeadLetter(ctx context.Context, appID, topicSlug, subscriptionSlug, deadLetterID string) (*models.DeadLetter, error) {
+	deadLetter := models.DeadLetter{}
+
+	err := c.req().
+		Pathf("/teams/%s/apps/%s/topics/%s/subscriptions/%s/dead-letters/%s", c.team, appID, topicSlug, subscriptionSlug, deadLetterID).
+		ToJSON(&deadLetter).
+		Fetch(ctx)
+
+	if err != nil {
+		return nil, err
 	}
 
-	if newSub.FilterValue != "" {
-		body["filter_value"] = newSub.FilterValue
-		fmt.Printf("filter value: %s\n", newSub.FilterValue)
+	return &deadLetter, nil
+}
+
+type ReplayDeadLetters struct {
+	// IDs of the dead letters to replay. Ignored when All is set.
+	IDs []string `json:"ids,omitempty"`
+	All bool     `json:"all,omitempty"`
+}
+
+type ReplayDeadLettersResponse struct {
+	Replayed int `json:"replayed"`
+}
+
+func (c *SailhouseClient) ReplayDeadLetters(ctx context.Context, appID, topicSlug, subscriptionSlug string, replay ReplayDeadLetters) (ReplayDeadLettersResponse, error) {
+	if replay.All {
+		replay.IDs = nil
 	}
 
-	var sub models.Subscription
+	var resp ReplayDeadLettersResponse
 	err := c.req().
-		Pathf("/teams/%s/apps/%s/topics/%s/subscriptions", c.team, appID, newSub.TopicSlug).
-		BodyJSON(body).
-		ToJSON(&sub).
+		Pathf("/teams/%s/apps/%s/topics/%s/subscriptions/%s/dead-letters/replay", c.team, appID, topicSlug, subscriptionSlug).
+		BodyJSON(replay).
+		ToJSON(&resp).
 		Fetch(ctx)
 
-	return sub, err
+	return resp, err
 }
 
-func (c *SailhouseClient) DeleteSubscription(ctx context.Context, appID, topicSlug, subscriptionSlug string) error {
-	return c.req().
-		Pathf("/teams/%s/apps/%s/topics/%s/subscriptions/%s", c.team, appID, topicSlug, subscriptionSlug).
+type PurgeDeadLettersResponse struct {
+	Purged int `json:"purged"`
+}
+
+func (c *SailhouseClient) PurgeDeadLetters(ctx context.Context, appID, topicSlug, subscriptionSlug string) (PurgeDeadLettersResponse, error) {
+	var resp PurgeDeadLettersResponse
+	err := c.req().
+		Pathf("/teams/%s/apps/%s/topics/%s/subscriptions/%s/dead-letters", c.team, appID, topicSlug, subscriptionSlug).
 		Method("DELETE").
+		ToJSON(&resp).
 		Fetch(ctx)
+
+	return resp, err
 }
 
 type GetSchema struct {
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/sailhouse/sailhouse/schema"
+	"github.com/sailhouse/sailhouse/util/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	applyCmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Apply a schema file to the current app",
+		Long: `Apply a schema file to the current app.
+
+Topics and subscriptions in the schema that don't exist yet are created, and
+subscriptions whose settings differ are updated in place, keeping their
+pending events. Topics and subscriptions missing from the schema are deleted
+if they were created with the schema's key, or regardless of key when --prune
+is passed.
+
+The schema file is passed with --file, which has no -f shorthand because -f is
+the global --format flag.`,
+		Example: `  sailhouse apply --file sailhouse.yaml
+  sailhouse apply --file sailhouse.yaml --yes --format json`,
+		Args: cobra.NoArgs,
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[schema.Plan]) {
+			token := viper.GetString("token")
+
+			file := getSchemaFile(cmd)
+			prune, _ := cmd.Flags().GetBool("prune")
+			yes, _ := cmd.Flags().GetBool("yes")
+
+			desired, err := schema.Load(file)
+			if err != nil {
+				out.AddError("Failed to load schema", err)
+				return
+			}
+
+			if err := schema.Validate(desired); err != nil {
+				out.AddError(err.Error())
+				return
+			}
+
+			app := getApp()
+			client := newClient(token)
+
+			live, err := schema.FetchLive(context.Background(), client, app)
+			if err != nil {
+				out.AddError("Failed to fetch the current topics and subscriptions", err)
+				return
+			}
+
+			plan := schema.Diff(desired, live, schema.DiffOptions{Prune: prune})
+			if !plan.HasChanges() {
+				out.SetData(plan)
+				out.AddMessage(fmt.Sprintf("%s is up to date", app))
+				return
+			}
+
+			if !yes {
+				// The preview and prompt go to stderr so stdout only has the
+				// result, which matters with --format json.
+				for _, change := range plan.Changes {
+					fmt.Fprintln(os.Stderr, renderChange(change))
+				}
+				fmt.Fprintln(os.Stderr, renderPlanSummary(plan))
+				fmt.Fprintln(os.Stderr)
+
+				confirmed := false
+				err := ask(&survey.Confirm{
+					Message: fmt.Sprintf("Apply %d changes to %s?", len(plan.Changes), app),
+				}, &confirmed, "--yes to apply the changes", survey.WithStdio(os.Stdin, os.Stderr, os.Stderr))
+				if err != nil {
+					out.AddError("Apply not confirmed", err)
+					return
+				}
+
+				if !confirmed {
+					out.AddMessage("Apply cancelled")
+					return
+				}
+			}
+
+			applied, err := schema.Apply(context.Background(), client, app, plan, func(change schema.Change) {
+				out.AddMessage(renderChangeHeader(change))
+			})
+
+			plan.Changes = applied
+			out.SetData(plan)
+
+			if err != nil {
+				out.AddError("Failed to apply schema", err)
+				return
+			}
+
+			out.AddMessage(fmt.Sprintf("\nApplied %d changes to %s", len(applied), app))
+		}),
+	}
+
+	applyCmd.Flags().String("file", "sailhouse.yaml", "Path to the schema file (defaults to the project's schema when set)")
+	applyCmd.Flags().Bool("prune", false, "Delete topics and subscriptions missing from the schema, even if they weren't created by it")
+	applyCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+
+	rootCmd.AddCommand(applyCmd)
+}
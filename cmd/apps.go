@@ -3,12 +3,13 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"regexp"
+	"os"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/sailhouse/sailhouse/api"
 	"github.com/sailhouse/sailhouse/models"
+	"github.com/sailhouse/sailhouse/util"
 	"github.com/sailhouse/sailhouse/util/output"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -20,17 +21,16 @@ func init() {
 		Short: "Manage apps",
 	}
 
-	appCmd.AddCommand(&cobra.Command{
+	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List apps",
 		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[[]models.App]) {
 			token := viper.GetString("token")
-			client := api.NewSailhouseClient(token)
+			client := newClient(token)
 
-			apps, err := client.GetApps(context.Background())
+			apps, more, err := listItems(context.Background(), cmd, client.ListApps(listOptions(cmd)))
 			if err != nil {
 				out.AddError("Failed to get apps", err)
-				out.Print()
 				return
 			}
 
@@ -44,9 +44,15 @@ func init() {
 				table.AddRow(slug)
 			}
 
+			if more {
+				table.SetFooter(moreMessage(len(apps), "apps"))
+			}
+
 			out.SetTable(table)
 		}),
-	})
+	}
+	addListFlags(listCmd, "apps")
+	appCmd.AddCommand(listCmd)
 
 	addAppCommand := &cobra.Command{
 		Use:   "create [app-slug]",
@@ -55,57 +61,228 @@ func init() {
 		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[models.App]) {
 			token := viper.GetString("token")
 
-			client := api.NewSailhouseClient(token)
+			client := newClient(token)
 
 			var appName string
 			if len(args) == 1 {
 				appName = args[0]
 			} else {
-				survey.AskOne(
+				err := ask(
 					&survey.Input{
 						Message: "What slug should the app have?",
 						Help:    "The app slug is used to identify your app in the Sailhouse API. It must be unique and can only contain lowercase letters, numbers, and dashes.",
 					},
 					&appName,
+					"the app slug as an argument: sailhouse apps create [app-slug]",
 					survey.WithValidator(func(ans any) error {
-						appSlugRegex := "^[a-z0-9-]+$"
-						regex := regexp.MustCompile(appSlugRegex)
-						if !regex.MatchString(ans.(string)) {
+						if !util.IsValidSlug(ans.(string)) {
 							return fmt.Errorf("Slug can only contain lowercase letters, numbers or dashes")
 						}
 						return nil
 					}),
 				)
+				if err != nil {
+					out.AddError("Missing app slug", err)
+					return
+				}
 			}
 
 			if appName == "" {
-				out.AddError("App slug cannot be empty", nil)
+				out.AddCodedError(output.CodeValidation, "App slug cannot be empty")
 				return
 			}
 
-			appSlugRegex := "^[a-z0-9-]+$"
-			regex := regexp.MustCompile(appSlugRegex)
-			if !regex.MatchString(appName) {
-				out.AddError("Slug can only contain lowercase letters, numbers or dashes")
+			if !util.IsValidSlug(appName) {
+				out.AddCodedError(output.CodeValidation, "Slug can only contain lowercase letters, numbers or dashes")
 				return
 			}
 
-			err := client.CreateApp(context.Background(), appName)
+			app, err := client.CreateApp(context.Background(), api.CreateApp{Slug: appName})
 
 			if err != nil {
 				out.AddError("Failed to create app", err)
 				return
 			}
 
-			out.AddMessage(fmt.Sprintf("Created app %s", appName))
-			out.SetData(models.App{
-				ID:   appName,
-				Slug: appName,
-			})
+			out.AddMessage(fmt.Sprintf("Created app %s", app.Slug))
+			out.SetData(app)
 		}),
 	}
 
 	appCmd.AddCommand(addAppCommand)
 
+	appCmd.AddCommand(&cobra.Command{
+		Use:   "view [app-slug]",
+		Short: "Show an app and what's in it",
+		Args:  cobra.MaximumNArgs(1),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[AppDetails]) {
+			token := viper.GetString("token")
+
+			var appSlug string
+			if len(args) == 1 {
+				appSlug = args[0]
+			} else {
+				appSlug = getApp()
+			}
+
+			client := newClient(token)
+
+			details, err := getAppDetails(context.Background(), client, appSlug)
+			if err != nil {
+				out.AddError("Failed to get app", err)
+				return
+			}
+
+			out.SetData(details)
+			out.AddMessage(fmt.Sprintf("ID: %s", details.ID))
+			out.AddMessage(fmt.Sprintf("Slug: %s", details.Slug))
+			out.AddMessage(fmt.Sprintf("Topics: %d", details.TopicCount))
+			out.AddMessage(fmt.Sprintf("Subscriptions: %d", details.SubscriptionCount))
+			out.AddMessage(fmt.Sprintf("Tokens: %d", details.TokenCount))
+			out.AddMessage(fmt.Sprintf("Events published: %d", details.EventCount))
+		}),
+	})
+
+	appCmd.AddCommand(&cobra.Command{
+		Use:   "rename [app-slug] [new-slug]",
+		Short: "Change an app's slug",
+		Long: `Change an app's slug.
+
+Anything referring to the app by its old slug, like SAILHOUSE_APP in scripts,
+needs updating. The project config is updated for you when it uses the app.`,
+		Args: cobra.ExactArgs(2),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[models.App]) {
+			token := viper.GetString("token")
+			oldSlug := args[0]
+			newSlug := args[1]
+
+			if !util.IsValidSlug(newSlug) {
+				out.AddCodedError(output.CodeValidation, "Slug can only contain lowercase letters, numbers or dashes")
+				return
+			}
+
+			client := newClient(token)
+
+			app, err := client.UpdateApp(context.Background(), oldSlug, api.UpdateApp{Slug: &newSlug})
+			if err != nil {
+				out.AddError("Failed to rename app", err)
+				return
+			}
+
+			out.SetData(app)
+			out.AddMessage(fmt.Sprintf("Renamed app %s to %s", oldSlug, app.Slug))
+
+			if activeProject != nil && activeProject.RenameApp(viper.GetString("team"), oldSlug, app.Slug) {
+				if err := activeProject.Save(); err != nil {
+					out.AddError("Failed to update the project config", err)
+					return
+				}
+				out.AddMessage(fmt.Sprintf("Updated %s", activeProject.Path))
+			}
+		}),
+	})
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete [app-slug]",
+		Short: "Delete an app and everything in it",
+		Long: `Delete an app and everything in it.
+
+The app's topics, subscriptions, tokens and undelivered events are deleted
+with it. You'll be asked to type the app's slug to confirm, pass --yes to
+skip this in scripts.`,
+		Example: `  sailhouse apps delete preview-my-branch --yes`,
+		Args:    cobra.ExactArgs(1),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[AppDetails]) {
+			token := viper.GetString("token")
+			yes, _ := cmd.Flags().GetBool("yes")
+			appSlug := args[0]
+
+			client := newClient(token)
+
+			details, err := getAppDetails(context.Background(), client, appSlug)
+			if err != nil {
+				out.AddError("Failed to get app", err)
+				return
+			}
+
+			if !yes {
+				warning := fmt.Sprintf("Deleting %s will also delete %d topics, %d subscriptions and %d tokens. This can't be undone.",
+					appSlug, details.TopicCount, details.SubscriptionCount, details.TokenCount)
+				fmt.Fprintln(os.Stderr, lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(warning))
+				fmt.Fprintln(os.Stderr)
+
+				var typed string
+				err := ask(&survey.Input{
+					Message: fmt.Sprintf("Type %s to confirm:", appSlug),
+				}, &typed, "--yes to confirm the deletion")
+				if err != nil {
+					out.AddError("Deletion not confirmed", err)
+					return
+				}
+
+				if typed != appSlug {
+					out.AddMessage("Slug didn't match, deletion cancelled")
+					return
+				}
+			}
+
+			if err := client.DeleteApp(context.Background(), appSlug); err != nil {
+				out.AddError("Failed to delete app", err)
+				return
+			}
+
+			out.SetData(details)
+			out.AddMessage(fmt.Sprintf("Deleted app %s", appSlug))
+		}),
+	}
+	deleteCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+
+	appCmd.AddCommand(deleteCmd)
+
 	rootCmd.AddCommand(appCmd)
 }
+
+type AppDetails struct {
+	models.App
+	TopicCount        int `json:"topic_count"`
+	SubscriptionCount int `json:"subscription_count"`
+	TokenCount        int `json:"token_count"`
+	EventCount        int `json:"event_count"`
+}
+
+func getAppDetails(ctx context.Context, client *api.SailhouseClient, appSlug string) (AppDetails, error) {
+	app, err := client.GetApp(ctx, appSlug)
+	if err != nil {
+		return AppDetails{}, err
+	}
+
+	details := AppDetails{App: *app}
+
+	topics, err := client.GetTopics(ctx, appSlug)
+	if err != nil {
+		return AppDetails{}, err
+	}
+	details.TopicCount = len(topics)
+
+	for _, topic := range topics {
+		subscriptions, err := client.GetSubscriptions(ctx, appSlug, topic.Slug)
+		if err != nil {
+			return AppDetails{}, err
+		}
+		details.SubscriptionCount += len(subscriptions)
+	}
+
+	tokens, err := client.GetTokens(ctx, appSlug)
+	if err != nil {
+		return AppDetails{}, err
+	}
+	details.TokenCount = len(tokens)
+
+	usage, err := client.GetAppUsage(ctx, appSlug)
+	if err != nil {
+		return AppDetails{}, err
+	}
+	details.EventCount = usage.Count
+
+	return details, nil
+}
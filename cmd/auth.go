@@ -4,83 +4,463 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
+	"os/signal"
+	"strings"
 	"time"
 
-	"github.com/carlmjohnson/requests"
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/sailhouse/sailhouse/api"
 	"github.com/sailhouse/sailhouse/config"
+	"github.com/sailhouse/sailhouse/models"
 	"github.com/sailhouse/sailhouse/publicid"
+	"github.com/sailhouse/sailhouse/util/output"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
+// authPollInterval is how often login checks whether the code has been
+// approved.
+const authPollInterval = 5 * time.Second
+
+type LoginResult struct {
+	Profile     string `json:"profile"`
+	Team        string `json:"team"`
+	Credentials string `json:"credentials"`
+}
+
+type WhoAmI struct {
+	User    models.User `json:"user"`
+	Teams   []string    `json:"teams"`
+	Team    string      `json:"team"`
+	Profile string      `json:"profile"`
+	// TokenSource is where the token came from, the profile or
+	// SAILHOUSE_TOKEN.
+	TokenSource string `json:"token_source"`
+}
+
 func init() {
 	authCmd := &cobra.Command{
 		Use:   "auth",
 		Short: "Authenticate with Sailhouse",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			code := publicid.Must()
-
-			// open a browser to the auth url
-			url := fmt.Sprintf("https://app.sailhouse.dev/auth?code=%s", code)
-			fmt.Printf("Please authenticate at %s\n", url)
-
-			exec.Command("open", url).Run()
-
-			// wait for the user to authenticate
-			fmt.Println("Waiting for authentication...")
-			fmt.Print("Will time out after 5 minutes\n\n\n\n")
-			startTime := time.Now()
-			var token string
-			for {
-				if time.Since(startTime) > 5*time.Minute {
-					fmt.Println("Timed out waiting for authentication")
-					os.Exit(1)
-				}
-				ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
+		Long: `Authenticate with Sailhouse.
+
+Running auth on its own is the same as auth login.`,
+		Args: cobra.NoArgs,
+		Run:  output.WithOutput(login),
+	}
+	addLoginFlags(authCmd)
+
+	loginCmd := &cobra.Command{
+		Use:   "login",
+		Short: "Sign in with your Sailhouse account",
+		Long: `Sign in with your Sailhouse account.
+
+Opens the dashboard in your browser to approve the login. Over SSH, without a
+display, or with --no-browser, it prints a link and a code to enter on any
+device instead. The browser is chosen with $BROWSER, falling back to the
+system default.
+
+For CI and other places without a person at the keyboard, pass
+--with-token to read a token from stdin instead.
+
+When you belong to more than one team you're asked which to use, or pass
+--team.`,
+		Example: `  sailhouse auth login
+  sailhouse auth login --no-browser --wait 10m
+  sailhouse auth login --team acme
+  echo "$SAILHOUSE_USER_TOKEN" | sailhouse auth login --with-token --team acme`,
+		Args: cobra.NoArgs,
+		Run:  output.WithOutput(login),
+	}
+	addLoginFlags(loginCmd)
+
+	authCmd.AddCommand(loginCmd)
+
+	logoutCmd := &cobra.Command{
+		Use:   "logout",
+		Short: "Remove the profile's saved token",
+		Long: `Remove the profile's saved token.
 
-				tokenResponse := map[string]string{}
+The token is deleted from the profile or credential store; the team and other
+settings are kept. Pass --revoke to also revoke the token so it stops working
+everywhere, not just on this machine.`,
+		Args: cobra.NoArgs,
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[LoginResult]) {
+			revoke, _ := cmd.Flags().GetBool("revoke")
 
-				err := requests.URL("https://api.sailhouse.dev/user/auth/token").Param("code", code).ToJSON(&tokenResponse).Fetch(ctx)
-				if err != nil {
-					respError := &requests.ResponseError{}
-					errors.As(err, &respError)
-					if respError.StatusCode == 404 {
-						time.Sleep(5 * time.Second)
-						continue
+			profile, err := config.LoadProfile(viper.GetString("profile"))
+			if err != nil {
+				out.AddError("Failed to load profile", err)
+				return
+			}
+
+			token, err := profile.LoadToken()
+			if err != nil && !errors.Is(err, config.ErrCredentialNotFound) {
+				out.AddError("Failed to load credentials", err)
+				return
+			}
+
+			if token == "" {
+				out.AddMessage(fmt.Sprintf("Not signed in to profile %s", profile.Name))
+			} else {
+				// Revoke first, the token can't be revoked once it's gone.
+				if revoke {
+					if err := newClient(token).RevokeCurrentToken(context.Background()); err != nil {
+						out.AddError("Failed to revoke the token, it's still saved", err)
+						return
 					}
+				}
+
+				credentials := profile.Credentials
+				if credentials == "" {
+					credentials = config.CredentialsPlaintext
+				}
+
+				if err := profile.DeleteToken(); err != nil {
+					out.AddError(fmt.Sprintf("Failed to remove the token from the %s credential store", credentials), err)
+					return
+				}
+				profile.Credentials = ""
 
-					return err
+				if err := profile.SaveProfile(); err != nil {
+					out.AddError("Failed to save profile", err)
+					return
 				}
 
-				fmt.Println("Authenticated!")
-				token = tokenResponse["token"]
-				break
+				if revoke {
+					out.AddMessage(fmt.Sprintf("Revoked the token and signed out of profile %s", profile.Name))
+				} else {
+					out.AddMessage(fmt.Sprintf("Signed out of profile %s", profile.Name))
+				}
+			}
+
+			if os.Getenv("SAILHOUSE_TOKEN") != "" {
+				out.AddMessage("SAILHOUSE_TOKEN is still set and will keep being used")
+			}
+
+			out.SetData(LoginResult{Profile: profile.Name, Team: profile.Team})
+		}),
+	}
+	logoutCmd.Flags().Bool("revoke", false, "Also revoke the token so it stops working everywhere")
+
+	authCmd.AddCommand(logoutCmd)
+
+	authCmd.AddCommand(&cobra.Command{
+		Use:   "token",
+		Short: "Print the active token",
+		Long: `Print the active token, from SAILHOUSE_TOKEN or the profile, for use in
+scripts.`,
+		Example: `  curl -H "Authorization: $(sailhouse auth token)" https://api.sailhouse.dev/teams`,
+		Args:    cobra.NoArgs,
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[string]) {
+			loadToken()
+			token := viper.GetString("token")
+
+			if token == "" {
+				out.AddError("Not signed in", notSignedIn())
+				return
 			}
 
-			client := api.NewSailhouseClient(token)
+			out.SetData(token)
+			out.AddMessage(token)
+		}),
+	})
+
+	authCmd.AddCommand(&cobra.Command{
+		Use:   "whoami",
+		Short: "Show who the active token belongs to",
+		Long: `Show who the active token belongs to.
+
+Calls the API, so it also checks the token is still valid. Exits with status 4
+when it isn't.`,
+		Args: cobra.NoArgs,
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[WhoAmI]) {
+			loadToken()
+			token := viper.GetString("token")
+
+			if token == "" {
+				out.AddError("Not signed in", notSignedIn())
+				return
+			}
+
+			source := "profile"
+			if os.Getenv("SAILHOUSE_TOKEN") != "" {
+				source = "SAILHOUSE_TOKEN"
+			}
+
+			client := newClient(token)
+
+			user, err := client.GetCurrentUser(context.Background())
+			if err != nil {
+				out.AddError(fmt.Sprintf("The token from %s isn't valid", source), err)
+				return
+			}
 
 			teams, err := client.GetTeams(context.Background())
 			if err != nil {
-				return err
+				out.AddError("Error getting teams", err)
+				return
 			}
 
-			if len(teams) == 0 {
-				fmt.Println("You don't have access to any teams")
-				os.Exit(1)
+			whoami := WhoAmI{
+				User:        user,
+				Teams:       []string{},
+				Team:        viper.GetString("team"),
+				Profile:     viper.GetString("profile"),
+				TokenSource: source,
 			}
+			for _, team := range teams {
+				whoami.Teams = append(whoami.Teams, team.Slug)
+			}
+
+			out.SetData(whoami)
+
+			name := user.Email
+			if user.Name != "" {
+				name = fmt.Sprintf("%s (%s)", user.Name, user.Email)
+			}
+
+			out.AddMessage(fmt.Sprintf("Signed in as %s", name))
+			out.AddMessage(fmt.Sprintf("Token: valid, from %s (%s)", source, maskToken(token)))
+			out.AddMessage(fmt.Sprintf("Profile: %s", whoami.Profile))
+			out.AddMessage(fmt.Sprintf("Team: %s", whoami.Team))
+			out.AddMessage(fmt.Sprintf("Teams: %s", strings.Join(whoami.Teams, ", ")))
+		}),
+	})
+
+	rootCmd.AddCommand(authCmd)
+}
+
+func addLoginFlags(cmd *cobra.Command) {
+	cmd.Flags().String("credentials", "", "Where to store the token [keyring | file | plaintext] (defaults to keyring when available)")
+	cmd.Flags().Bool("no-browser", false, "Print a link and code to sign in on another device instead of opening a browser")
+	cmd.Flags().Duration("wait", 5*time.Minute, "How long to wait for the login to be approved")
+	cmd.Flags().Bool("with-token", false, "Read a token from stdin instead of signing in with the browser")
+	cmd.MarkFlagsMutuallyExclusive("with-token", "no-browser")
+	cmd.MarkFlagsMutuallyExclusive("with-token", "wait")
+}
+
+func login(cmd *cobra.Command, args []string, out *output.Output[LoginResult]) {
+	withToken, _ := cmd.Flags().GetBool("with-token")
 
-			profile := config.LoadProfile()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-			profile.Token = token
-			profile.Team = teams[0].Slug
+	var token string
+	if withToken {
+		if !stdinIsPiped() {
+			out.AddCodedError(output.CodeValidation, "--with-token reads the token from stdin, e.g. echo $TOKEN | sailhouse auth login --with-token")
+			return
+		}
 
-			profile.SaveProfile()
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			out.AddError("Failed to read the token from stdin", err)
+			return
+		}
 
-			return nil
-		},
+		token = strings.TrimSpace(string(input))
+		if token == "" {
+			out.AddCodedError(output.CodeValidation, "no token on stdin")
+			return
+		}
+	} else {
+		var ok bool
+		token, ok = browserLogin(ctx, cmd, out)
+		if !ok {
+			return
+		}
 	}
 
-	rootCmd.AddCommand(authCmd)
+	client := newClient(token)
+
+	// Also checks the token works before it's saved.
+	teams, err := client.GetTeams(ctx)
+	if err != nil {
+		out.AddError("Error getting teams", err)
+		return
+	}
+
+	if len(teams) == 0 {
+		out.AddError("You don't have access to any teams")
+		return
+	}
+
+	team, err := selectLoginTeam(cmd, teams)
+	if err != nil {
+		out.AddError("Failed to choose a team", err)
+		return
+	}
+
+	profile, err := config.LoadProfile(viper.GetString("profile"))
+	if err != nil {
+		out.AddError("Failed to load profile", err)
+		return
+	}
+
+	store, _ := cmd.Flags().GetString("credentials")
+	if store == "" {
+		store = config.DefaultCredentialStore()
+
+		// Without a keyring, e.g. on CI, the file store would need a
+		// passphrase that can't be asked for.
+		if store == config.CredentialsFile && !inputAvailable() && os.Getenv("SAILHOUSE_PASSPHRASE") == "" {
+			store = config.CredentialsPlaintext
+			warnText := lipgloss.NewStyle().Foreground(lipgloss.Color("220")).Render("No keyring available and no passphrase for the encrypted file store, saving the token in plaintext. Pass --credentials file with SAILHOUSE_PASSPHRASE set to encrypt it.")
+			fmt.Fprintln(os.Stderr, warnText)
+		}
+	}
+
+	// Don't leave a stale token behind when switching stores.
+	if profile.Credentials != store {
+		profile.DeleteToken()
+	}
+
+	if err := profile.StoreToken(store, token); err != nil {
+		out.AddError(fmt.Sprintf("Failed to save token to the %s credential store", store), err)
+		return
+	}
+
+	profile.Team = team.Slug
+
+	if err := profile.SaveProfile(); err != nil {
+		out.AddError("Failed to save profile", err)
+		return
+	}
+
+	out.SetData(LoginResult{Profile: profile.Name, Team: team.Slug, Credentials: store})
+	out.AddMessage(fmt.Sprintf("Signed in to team %s", team.Slug))
+}
+
+func notSignedIn() error {
+	return output.WithCode(output.CodeUnauthorized, errors.New("run `sailhouse auth login` or set SAILHOUSE_TOKEN"))
+}
+
+// browserLogin signs in through the dashboard, reporting failures to out.
+func browserLogin(ctx context.Context, cmd *cobra.Command, out *output.Output[LoginResult]) (string, bool) {
+	noBrowser, _ := cmd.Flags().GetBool("no-browser")
+	wait, _ := cmd.Flags().GetDuration("wait")
+
+	if wait <= 0 {
+		out.AddCodedError(output.CodeValidation, "--wait must be positive")
+		return "", false
+	}
+
+	code := publicid.Must()
+	webURL := strings.TrimSuffix(viper.GetString("web_url"), "/")
+	loginURL := fmt.Sprintf("%s/auth?code=%s", webURL, code)
+
+	// Instructions go to stderr so they're seen even with --format json.
+	if !noBrowser && canOpenBrowser() && openBrowser(loginURL) == nil {
+		fmt.Fprintf(os.Stderr, "Opened %s in your browser\n\n", loginURL)
+	} else {
+		fmt.Fprintf(os.Stderr, "To sign in, open %s/auth on any device and enter the code\n\n    %s\n\nor go straight to %s\n\n", webURL, formatAuthCode(code), loginURL)
+	}
+	fmt.Fprintf(os.Stderr, "Waiting for the login to be approved, press Ctrl-C to cancel...\n")
+
+	client := newClient("")
+
+	token, err := waitForLogin(ctx, client, code, wait)
+	if err != nil {
+		switch {
+		case ctx.Err() != nil:
+			out.AddError("Login cancelled")
+		case err == context.DeadlineExceeded:
+			out.AddError(fmt.Sprintf("Timed out after %s waiting for the login to be approved, try again or pass a longer --wait", wait))
+		default:
+			out.AddError("Failed to sign in", err)
+		}
+		return "", false
+	}
+
+	return token, true
+}
+
+// waitForLogin polls until the code is approved, wait runs out or ctx is
+// cancelled.
+func waitForLogin(ctx context.Context, client *api.SailhouseClient, code string, wait time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	for {
+		token, err := client.ExchangeAuthCode(ctx, code)
+		if err == nil {
+			return token, nil
+		}
+
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		if !errors.Is(err, api.ErrNotFound) {
+			return "", err
+		}
+
+		timer := time.NewTimer(authPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// formatAuthCode groups a login code so it's easier to read out and type.
+func formatAuthCode(code string) string {
+	groups := []string{}
+	for len(code) > 4 {
+		groups = append(groups, code[:4])
+		code = code[4:]
+	}
+
+	return strings.Join(append(groups, code), "-")
+}
+
+// selectLoginTeam picks the team to sign in to: the one given with --team or
+// SAILHOUSE_TEAM, the only one available, or the one chosen at a prompt.
+func selectLoginTeam(cmd *cobra.Command, teams []models.Team) (models.Team, error) {
+	if cmd.Flags().Changed("team") || os.Getenv("SAILHOUSE_TEAM") != "" {
+		slug := viper.GetString("team")
+		for _, team := range teams {
+			if team.Slug == slug {
+				return team, nil
+			}
+		}
+
+		return models.Team{}, output.WithCode(output.CodeNotFound, fmt.Errorf("you don't have access to team %s", slug))
+	}
+
+	if len(teams) == 1 {
+		return teams[0], nil
+	}
+
+	options := []string{}
+	for _, team := range teams {
+		options = append(options, team.Slug)
+	}
+
+	prompt := &survey.Select{Message: "Team:", Options: options}
+	// Signing in again keeps the current team selected.
+	for _, team := range teams {
+		if team.Slug == activeProfile.Team {
+			prompt.Default = team.Slug
+		}
+	}
+
+	var slug string
+	if err := ask(prompt, &slug, "--team to choose a team"); err != nil {
+		return models.Team{}, err
+	}
+
+	for _, team := range teams {
+		if team.Slug == slug {
+			return team, nil
+		}
+	}
+
+	return models.Team{}, output.WithCode(output.CodeNotFound, fmt.Errorf("team %s not found", slug))
 }
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+var errNoBrowser = errors.New("no browser available")
+
+// canOpenBrowser reports whether a browser is likely to open somewhere the
+// user can see it. SSH sessions and Linux without a display can't, unless
+// $BROWSER says otherwise.
+func canOpenBrowser() bool {
+	if os.Getenv("BROWSER") != "" {
+		return true
+	}
+
+	if os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "" {
+		return false
+	}
+
+	if runtime.GOOS == "linux" || runtime.GOOS == "freebsd" || runtime.GOOS == "openbsd" {
+		return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+	}
+
+	return true
+}
+
+// openBrowser opens url with $BROWSER, which may list several commands
+// separated by colons, or the platform's opener.
+func openBrowser(url string) error {
+	commands := [][]string{}
+
+	if browser := os.Getenv("BROWSER"); browser != "" {
+		for _, command := range strings.Split(browser, string(os.PathListSeparator)) {
+			args := strings.Fields(command)
+			if len(args) == 0 {
+				continue
+			}
+
+			// Following the $BROWSER convention, %s marks where the URL
+			// goes, otherwise it's appended.
+			replaced := false
+			for i, arg := range args {
+				if strings.Contains(arg, "%s") {
+					args[i] = strings.ReplaceAll(arg, "%s", url)
+					replaced = true
+				}
+			}
+			if !replaced {
+				args = append(args, url)
+			}
+
+			commands = append(commands, args)
+		}
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		commands = append(commands, []string{"open", url})
+	case "windows":
+		commands = append(commands, []string{"rundll32", "url.dll,FileProtocolHandler", url})
+	default:
+		commands = append(commands, []string{"xdg-open", url}, []string{"wslview", url})
+	}
+
+	for _, args := range commands {
+		path, err := exec.LookPath(args[0])
+		if err != nil {
+			continue
+		}
+
+		// Some openers block until the browser closes, so don't wait.
+		c := exec.Command(path, args[1:]...)
+		if err := c.Start(); err != nil {
+			continue
+		}
+		go c.Wait()
+
+		return nil
+	}
+
+	return errNoBrowser
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/sailhouse/sailhouse/api"
+	"github.com/spf13/viper"
+)
+
+// newClient creates an API client for the active team honoring --timeout and
+// --retries. SAILHOUSE_DEBUG logs every request to stderr.
+func newClient(token string) *api.SailhouseClient {
+	retry := api.DefaultRetryPolicy()
+	retry.MaxRetries = viper.GetInt("retries")
+
+	opts := []api.ClientOption{
+		api.WithTeam(viper.GetString("team")),
+		api.WithBaseURL(viper.GetString("api_url")),
+		api.WithUserAgent("sailhouse-cli/" + viper.GetString("version")),
+		api.WithRetry(retry),
+		api.WithTimeout(viper.GetDuration("timeout")),
+	}
+
+	if os.Getenv("SAILHOUSE_DEBUG") != "" {
+		opts = append(opts, api.WithLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	}
+
+	return api.NewSailhouseClient(token, opts...)
+}
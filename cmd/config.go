@@ -0,0 +1,357 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sailhouse/sailhouse/config"
+	"github.com/sailhouse/sailhouse/util/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type ConfigEntry struct {
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	Global  string `json:"global,omitempty"`
+	Project string `json:"project,omitempty"`
+	Scope   string `json:"scope,omitempty"`
+}
+
+type ConfigPaths struct {
+	Global  string `json:"global"`
+	Project string `json:"project,omitempty"`
+}
+
+// configScope reads --global and --project, returning "" when neither is set.
+func configScope(cmd *cobra.Command) string {
+	if global, _ := cmd.Flags().GetBool("global"); global {
+		return config.ScopeGlobal
+	}
+
+	if project, _ := cmd.Flags().GetBool("project"); project {
+		return config.ScopeProject
+	}
+
+	return ""
+}
+
+// settingScope picks the scope to write a setting to, checking the setting
+// supports it.
+func settingScope(cmd *cobra.Command, setting config.Setting) (string, error) {
+	scope := configScope(cmd)
+	if scope == "" {
+		scope = setting.DefaultScope()
+	}
+
+	if !setting.HasScope(scope) {
+		return "", fmt.Errorf("%s can't be set in %s config", setting.Key, scope)
+	}
+
+	if scope == config.ScopeProject && activeProject == nil {
+		return "", errors.New("not in a project, run `sailhouse init` first")
+	}
+
+	return scope, nil
+}
+
+func scopeLocation(scope string) string {
+	if scope == config.ScopeProject {
+		return activeProject.Path
+	}
+
+	return fmt.Sprintf("profile %s", viper.GetString("profile"))
+}
+
+// writeSetting sets or, with an empty value, unsets a key in the given scope.
+func writeSetting(scope, key, value string) error {
+	if scope == config.ScopeProject {
+		activeProject.SetSetting(key, value)
+		return activeProject.Save()
+	}
+
+	profile, err := config.LoadProfile(viper.GetString("profile"))
+	if err != nil {
+		return err
+	}
+
+	profile.SetSetting(key, value)
+	return profile.SaveProfile()
+}
+
+func init() {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Read and write settings",
+		Long: `Read and write settings.
+
+Global settings are stored in the active profile in ~/.sailhouse/profile.toml,
+project settings in the nearest .sailhouse/config.yaml. Without --global or
+--project, get shows the value in effect and set writes to the key's default
+scope: the profile for team, api_url and web_url, the project for app, topic
+and schema.`,
+		Example: `  sailhouse config set team acme
+  sailhouse config set topic order-created
+  sailhouse config set team acme-staging --project
+  sailhouse config get app`,
+	}
+	configCmd.PersistentFlags().Bool("global", false, "Use the active profile")
+	configCmd.PersistentFlags().Bool("project", false, "Use the project config")
+	configCmd.MarkFlagsMutuallyExclusive("global", "project")
+
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "get [key]",
+		Short: "Print a setting",
+		Args:  cobra.ExactArgs(1),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[ConfigEntry]) {
+			setting, err := config.LookupSetting(args[0])
+			if err != nil {
+				out.AddError(err.Error())
+				return
+			}
+
+			entry := ConfigEntry{Key: setting.Key, Scope: configScope(cmd)}
+			switch entry.Scope {
+			case config.ScopeGlobal:
+				entry.Value = activeProfile.GetSetting(setting.Key)
+			case config.ScopeProject:
+				if activeProject == nil {
+					out.AddError("Not in a project, run `sailhouse init` first")
+					return
+				}
+				entry.Value = activeProject.GetSetting(setting.Key)
+			default:
+				entry.Value = viper.GetString(setting.Key)
+			}
+
+			if entry.Value == "" {
+				out.AddError(fmt.Sprintf("%s is not set", setting.Key))
+				return
+			}
+
+			out.SetData(entry)
+			out.AddMessage(entry.Value)
+		}),
+	})
+
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "set [key] [value]",
+		Short: "Change a setting",
+		Args:  cobra.ExactArgs(2),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[ConfigEntry]) {
+			setting, err := config.LookupSetting(args[0])
+			if err != nil {
+				out.AddError(err.Error())
+				return
+			}
+
+			value := args[1]
+			if setting.Validate != nil {
+				if err := setting.Validate(value); err != nil {
+					out.AddError(fmt.Sprintf("Invalid value for %s", setting.Key), err)
+					return
+				}
+			}
+
+			scope, err := settingScope(cmd, setting)
+			if err != nil {
+				out.AddError(err.Error())
+				return
+			}
+
+			if err := writeSetting(scope, setting.Key, value); err != nil {
+				out.AddError(fmt.Sprintf("Failed to set %s", setting.Key), err)
+				return
+			}
+
+			out.SetData(ConfigEntry{Key: setting.Key, Value: value, Scope: scope})
+			out.AddMessage(fmt.Sprintf("Set %s to %s in %s", setting.Key, value, scopeLocation(scope)))
+		}),
+	})
+
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "unset [key]",
+		Short: "Remove a setting",
+		Args:  cobra.ExactArgs(1),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[ConfigEntry]) {
+			setting, err := config.LookupSetting(args[0])
+			if err != nil {
+				out.AddError(err.Error())
+				return
+			}
+
+			scope, err := settingScope(cmd, setting)
+			if err != nil {
+				out.AddError(err.Error())
+				return
+			}
+
+			if err := writeSetting(scope, setting.Key, ""); err != nil {
+				out.AddError(fmt.Sprintf("Failed to unset %s", setting.Key), err)
+				return
+			}
+
+			out.SetData(ConfigEntry{Key: setting.Key, Scope: scope})
+			out.AddMessage(fmt.Sprintf("Unset %s in %s", setting.Key, scopeLocation(scope)))
+		}),
+	})
+
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List settings and where they're set",
+		Args:  cobra.NoArgs,
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[[]ConfigEntry]) {
+			scope := configScope(cmd)
+			if scope == config.ScopeProject && activeProject == nil {
+				out.AddError("Not in a project, run `sailhouse init` first")
+				return
+			}
+
+			entries := []ConfigEntry{}
+			table := output.NewTable()
+			table.AddColumns("Key", "Value", "Global", "Project")
+
+			for _, setting := range config.Settings {
+				entry := ConfigEntry{
+					Key:    setting.Key,
+					Value:  viper.GetString(setting.Key),
+					Global: activeProfile.GetSetting(setting.Key),
+				}
+				if activeProject != nil {
+					entry.Project = activeProject.GetSetting(setting.Key)
+				}
+
+				if scope == config.ScopeGlobal && !setting.HasScope(config.ScopeGlobal) ||
+					scope == config.ScopeProject && !setting.HasScope(config.ScopeProject) {
+					continue
+				}
+
+				entries = append(entries, entry)
+				table.AddRow(entry.Key, entry.Value, entry.Global, entry.Project)
+			}
+
+			out.SetData(entries)
+			out.SetTable(table)
+		}),
+	})
+
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "edit",
+		Short: "Open the config file in your editor",
+		Long: `Open the config file in your editor.
+
+Opens the project config when in a project, otherwise the profile file. The
+editor is taken from $VISUAL or $EDITOR, falling back to vi.`,
+		Args: cobra.NoArgs,
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[ConfigPaths]) {
+			if !inputAvailable() {
+				out.AddError("Cannot open an editor", &NoInputError{Missing: "settings with `sailhouse config set` instead"})
+				return
+			}
+
+			scope := configScope(cmd)
+			if scope == "" {
+				scope = config.ScopeGlobal
+				if activeProject != nil {
+					scope = config.ScopeProject
+				}
+			}
+
+			var path string
+			switch scope {
+			case config.ScopeProject:
+				if activeProject == nil {
+					out.AddError("Not in a project, run `sailhouse init` first")
+					return
+				}
+				path = activeProject.Path
+			default:
+				path = config.ProfilePath()
+				if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+					file, err := config.LoadProfileFile()
+					if err != nil {
+						out.AddError("Failed to load profiles", err)
+						return
+					}
+					if err := file.Save(); err != nil {
+						out.AddError("Failed to save profiles", err)
+						return
+					}
+				}
+			}
+
+			editor := os.Getenv("VISUAL")
+			if editor == "" {
+				editor = os.Getenv("EDITOR")
+			}
+			if editor == "" {
+				editor = "vi"
+			}
+
+			// Editors are often configured with arguments, e.g. "code -w".
+			editorArgs := strings.Fields(editor)
+			editorCmd := exec.Command(editorArgs[0], append(editorArgs[1:], path)...)
+			editorCmd.Stdin = os.Stdin
+			editorCmd.Stdout = os.Stdout
+			editorCmd.Stderr = os.Stderr
+
+			if err := editorCmd.Run(); err != nil {
+				out.AddError(fmt.Sprintf("Failed to run %s", editor), err)
+				return
+			}
+
+			if scope == config.ScopeProject {
+				if _, err := config.LoadProject(path); err != nil {
+					out.AddError("The project config is no longer valid", err)
+					return
+				}
+			}
+
+			out.SetData(ConfigPaths{Global: config.ProfilePath(), Project: projectPath()})
+			out.AddMessage(fmt.Sprintf("Saved %s", path))
+		}),
+	})
+
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "path",
+		Short: "Print the location of the config files",
+		Args:  cobra.NoArgs,
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[ConfigPaths]) {
+			paths := ConfigPaths{Global: config.ProfilePath(), Project: projectPath()}
+			out.SetData(paths)
+
+			switch configScope(cmd) {
+			case config.ScopeGlobal:
+				out.AddMessage(paths.Global)
+			case config.ScopeProject:
+				if paths.Project == "" {
+					out.AddError("Not in a project, run `sailhouse init` first")
+					return
+				}
+				out.AddMessage(paths.Project)
+			default:
+				out.AddMessage(fmt.Sprintf("Global: %s", paths.Global))
+				if paths.Project != "" {
+					out.AddMessage(fmt.Sprintf("Project: %s", paths.Project))
+				} else {
+					cwd, _ := os.Getwd()
+					out.AddMessage(fmt.Sprintf("Project: none, `sailhouse init` would create %s", filepath.Join(cwd, config.ProjectDir, config.ProjectFileName)))
+				}
+			}
+		}),
+	})
+
+	rootCmd.AddCommand(configCmd)
+}
+
+func projectPath() string {
+	if activeProject == nil {
+		return ""
+	}
+
+	return activeProject.Path
+}
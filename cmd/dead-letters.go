@@ -1,9 +1,26 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sailhouse/sailhouse/api"
+	"github.com/sailhouse/sailhouse/models"
+	"github.com/sailhouse/sailhouse/util/output"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
+const deadLetterPageSize = 100
+
 func init() {
 	deadLetterCmd := &cobra.Command{
 		Use:   "dead-letters",
@@ -14,18 +31,297 @@ func init() {
 		Use:   "list [topic] [subscription]",
 		Short: "List dead letters for a subscription",
 		Args:  cobra.ExactArgs(2),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[[]models.DeadLetter]) {
+			token := viper.GetString("token")
+			app := getApp()
+
+			client := newClient(token)
+
+			deadLetters, more, err := listItems(context.Background(), cmd, client.ListDeadLetters(app, args[0], args[1], listOptions(cmd)))
+			if err != nil {
+				out.AddError("Failed to get dead letters", err)
+				return
+			}
+
+			out.SetData(deadLetters)
+
+			if len(deadLetters) == 0 {
+				out.AddMessage("No dead letters found")
+				return
+			}
+
+			table := output.NewTable()
+			table.AddColumns("ID", "Event", "Attempts", "Failed At", "Last Error")
+
+			for _, deadLetter := range deadLetters {
+				id := lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render(deadLetter.ID)
+				table.AddRow(
+					id,
+					deadLetter.EventID,
+					strconv.Itoa(deadLetter.Attempts),
+					deadLetter.FailedAt.Local().Format(time.DateTime),
+					truncate(deadLetter.LastError, 60),
+				)
+			}
+
+			if more {
+				table.SetFooter(moreMessage(len(deadLetters), "dead letters"))
+			}
+
+			out.SetTable(table)
+		}),
+	}
+	addListFlags(listCmd, "dead letters")
+
+	deadLetterCmd.AddCommand(listCmd)
+
+	deadLetterCmd.AddCommand(&cobra.Command{
+		Use:   "view [topic] [subscription] [id]",
+		Short: "View a dead letter",
+		Args:  cobra.ExactArgs(3),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[models.DeadLetter]) {
+			token := viper.GetString("token")
+			app := getApp()
+
+			client := newClient(token)
+
+			deadLetter, err := client.GetDeadLetter(context.Background(), app, args[0], args[1], args[2])
+			if err != nil {
+				out.AddError("Failed to get dead letter", err)
+				return
+			}
+
+			out.SetData(*deadLetter)
+			out.AddMessage(fmt.Sprintf("ID: %s", deadLetter.ID))
+			out.AddMessage(fmt.Sprintf("Event: %s", deadLetter.EventID))
+			out.AddMessage(fmt.Sprintf("Attempts: %d", deadLetter.Attempts))
+			out.AddMessage(fmt.Sprintf("Failed at: %s", deadLetter.FailedAt.Local().Format(time.DateTime)))
+			if deadLetter.LastError != "" {
+				out.AddMessage(fmt.Sprintf("Last error: %s", deadLetter.LastError))
+			}
+			keys := make([]string, 0, len(deadLetter.Metadata))
+			for k := range deadLetter.Metadata {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				out.AddMessage(fmt.Sprintf("Metadata %s: %s", k, deadLetter.Metadata[k]))
+			}
+			out.AddMessage("Data:")
+			out.AddMessage(prettyJSON(deadLetter.Data))
+		}),
+	})
+
+	replayCmd := &cobra.Command{
+		Use:   "replay [topic] [subscription] [id...]",
+		Short: "Replay dead letters back onto a subscription",
+		Long: `Replay dead letters back onto a subscription.
+
+Pass one or more dead letter IDs to replay them, --all to replay every dead
+letter for the subscription, or neither to pick from a list.`,
+		Args: cobra.MinimumNArgs(2),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[api.ReplayDeadLettersResponse]) {
+			token := viper.GetString("token")
+			app := getApp()
+
+			all, _ := cmd.Flags().GetBool("all")
+			topic := args[0]
+			subscription := args[1]
+			ids := args[2:]
+
+			if all && len(ids) > 0 {
+				out.AddError("Pass either dead letter IDs or --all, not both")
+				return
+			}
+
+			client := newClient(token)
+
+			if !all && len(ids) == 0 {
+				deadLetters, err := client.GetDeadLetters(context.Background(), app, topic, subscription, api.GetDeadLetters{Limit: deadLetterPageSize})
+				if err != nil {
+					out.AddError("Failed to get dead letters", err)
+					return
+				}
+
+				if len(deadLetters) == 0 {
+					out.AddMessage("No dead letters to replay")
+					return
+				}
+
+				options := []string{}
+				for _, deadLetter := range deadLetters {
+					options = append(options, deadLetter.ID)
+				}
+
+				err = ask(&survey.MultiSelect{
+					Message: "Dead letters to replay:",
+					Options: options,
+				}, &ids, "dead letter IDs as arguments or --all")
+				if err != nil {
+					out.AddError("Failed to select dead letters", err)
+					return
+				}
+
+				if len(ids) == 0 {
+					out.AddMessage("Nothing selected")
+					return
+				}
+			}
+
+			resp, err := client.ReplayDeadLetters(context.Background(), app, topic, subscription, api.ReplayDeadLetters{
+				IDs: ids,
+				All: all,
+			})
+			if err != nil {
+				out.AddError("Failed to replay dead letters", err)
+				return
+			}
+
+			out.SetData(resp)
+			out.AddMessage(fmt.Sprintf("Replayed %d dead letters to %s", resp.Replayed, subscription))
+		}),
+	}
+	replayCmd.Flags().Bool("all", false, "Replay every dead letter for the subscription")
+
+	deadLetterCmd.AddCommand(replayCmd)
+
+	purgeCmd := &cobra.Command{
+		Use:   "purge [topic] [subscription]",
+		Short: "Permanently delete all dead letters for a subscription",
+		Args:  cobra.ExactArgs(2),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[api.PurgeDeadLettersResponse]) {
+			token := viper.GetString("token")
+			app := getApp()
+
+			yes, _ := cmd.Flags().GetBool("yes")
+			topic := args[0]
+			subscription := args[1]
+
+			if !yes {
+				confirmed := false
+				err := ask(&survey.Confirm{
+					Message: fmt.Sprintf("Permanently delete all dead letters for %s/%s?", topic, subscription),
+				}, &confirmed, "--yes to confirm the purge")
+				if err != nil {
+					out.AddError("Purge not confirmed", err)
+					return
+				}
+
+				if !confirmed {
+					out.AddMessage("Purge cancelled")
+					return
+				}
+			}
+
+			client := newClient(token)
+
+			resp, err := client.PurgeDeadLetters(context.Background(), app, topic, subscription)
+			if err != nil {
+				out.AddError("Failed to purge dead letters", err)
+				return
+			}
+
+			out.SetData(resp)
+			out.AddMessage(fmt.Sprintf("Purged %d dead letters from %s", resp.Purged, subscription))
+		}),
+	}
+	purgeCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+
+	deadLetterCmd.AddCommand(purgeCmd)
+
+	exportCmd := &cobra.Command{
+		Use:   "export [topic] [subscription]",
+		Short: "Export dead letters as JSON Lines",
+		Args:  cobra.ExactArgs(2),
 		Run: func(cmd *cobra.Command, args []string) {
-			limit, err := cmd.Flags().GetInt("limit")
+			out := output.NewOutput[map[string]any]()
+			token := viper.GetString("token")
+			app := getApp()
+
+			path, _ := cmd.Flags().GetString("output")
+			topic := args[0]
+			subscription := args[1]
+
+			var w io.Writer = os.Stdout
+			if path != "" && path != "-" {
+				file, err := os.Create(path)
+				if err != nil {
+					out.AddError("Failed to create export file", err)
+					out.Print()
+					os.Exit(out.ExitCode())
+				}
+				defer file.Close()
+				w = file
+			}
+
+			client := newClient(token)
+			encoder := json.NewEncoder(w)
+
+			count := 0
+			var writeErr error
+			pager := client.ListDeadLetters(app, topic, subscription, api.ListOptions{PageSize: deadLetterPageSize})
+			err := pager.Each(context.Background(), func(deadLetter models.DeadLetter) bool {
+				if writeErr = encoder.Encode(deadLetter); writeErr != nil {
+					return false
+				}
+
+				count++
+				return true
+			})
 			if err != nil {
-				panic(err)
+				out.AddError("Failed to get dead letters", err)
+				out.Print()
+				os.Exit(out.ExitCode())
+			}
+			if writeErr != nil {
+				out.AddError("Failed to write dead letter", writeErr)
+				out.Print()
+				os.Exit(out.ExitCode())
 			}
 
-			if limit > 100 {
-				limit = 100
+			// When exporting to stdout the dead letters are the output, so
+			// there's no summary to mix into them.
+			if w == os.Stdout {
+				return
 			}
+
+			out.SetData(map[string]any{"exported": count, "path": path})
+			out.AddMessage(fmt.Sprintf("Exported %d dead letters to %s", count, path))
+			out.Print()
 		},
 	}
-	listCmd.Flags().IntP("limit", "l", 10, "Limit the number of dead letters to return (max 100)")
+	exportCmd.Flags().StringP("output", "o", "", "File to write to (defaults to stdout)")
+
+	deadLetterCmd.AddCommand(exportCmd)
 
 	rootCmd.AddCommand(deadLetterCmd)
 }
+
+// truncate shortens s to at most limit characters, marking the cut with an
+// ellipsis when there's room for one.
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+
+	if limit < 3 {
+		return string(runes[:max(limit, 0)])
+	}
+
+	return string(runes[:limit-3]) + "..."
+}
+
+func prettyJSON(data json.RawMessage) string {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return string(data)
+	}
+
+	pretty, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return string(data)
+	}
+
+	return string(pretty)
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		limit int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"exactly ten", 11, "exactly ten"},
+		{"connection refused", 10, "connect..."},
+		{"héllo wörld", 8, "héllo..."},
+		{"日本語のエラー", 5, "日本..."},
+		{"abcdef", 2, "ab"},
+		{"abcdef", 0, ""},
+	}
+
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.limit); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.limit, got, tt.want)
+		}
+	}
+}
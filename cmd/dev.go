@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sailhouse/sailhouse/emulator"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	devCmd := &cobra.Command{
+		Use:   "dev",
+		Short: "Local development tools",
+	}
+
+	serverCmd := &cobra.Command{
+		Use:   "server",
+		Short: "Run a local Sailhouse emulator",
+		Long: `Run a local Sailhouse emulator.
+
+The emulator serves the same API the CLI talks to, including publishing,
+pull subscriptions, push delivery with retries and dead letters. Point the CLI
+at it with --api-url or SAILHOUSE_API_URL. Any token is accepted until it's
+revoked, except that app tokens created in the emulator are held to their app,
+scope and expiry.
+
+State is kept in memory unless --data is set, in which case it is saved to
+that file and reloaded on the next start.`,
+		Example: `  sailhouse dev server --data .sailhouse/emulator.json
+  SAILHOUSE_API_URL=http://localhost:7070 sailhouse --team local topics list`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			addr, _ := cmd.Flags().GetString("addr")
+			dataFile, _ := cmd.Flags().GetString("data")
+			seedTeam, _ := cmd.Flags().GetString("seed-team")
+			seedApp, _ := cmd.Flags().GetString("seed-app")
+			maxAttempts, _ := cmd.Flags().GetInt("max-attempts")
+			visibilityTimeout, _ := cmd.Flags().GetDuration("visibility-timeout")
+			quiet, _ := cmd.Flags().GetBool("quiet")
+
+			logger := log.New(os.Stderr, "emulator: ", log.LstdFlags)
+			if quiet {
+				logger = nil
+			}
+
+			server, err := emulator.New(emulator.Options{
+				Team:              seedTeam,
+				App:               seedApp,
+				DataFile:          dataFile,
+				MaxAttempts:       maxAttempts,
+				VisibilityTimeout: visibilityTimeout,
+				Logger:            logger,
+			})
+			if err != nil {
+				exitWithError("Failed to start the emulator", err)
+			}
+
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				exitWithError(fmt.Sprintf("Failed to listen on %s", addr), err)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			go server.Run(ctx)
+
+			httpServer := &http.Server{Handler: server.Handler()}
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				httpServer.Shutdown(shutdownCtx)
+			}()
+
+			url := fmt.Sprintf("http://%s", listener.Addr())
+			fmt.Printf("Sailhouse emulator listening on %s\n", url)
+			fmt.Printf("Use it with: SAILHOUSE_API_URL=%s sailhouse --team %s ...\n", url, seedTeam)
+
+			if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				exitWithError("Emulator stopped", err)
+			}
+		},
+	}
+
+	serverCmd.Flags().String("addr", "127.0.0.1:7070", "Address to listen on")
+	serverCmd.Flags().String("data", "", "File to persist state to (defaults to in-memory)")
+	serverCmd.Flags().String("seed-team", "local", "Team to create on start")
+	serverCmd.Flags().String("seed-app", "local", "App to create in the seed team on start")
+	serverCmd.Flags().Int("max-attempts", 5, "Delivery attempts before an event is dead-lettered")
+	serverCmd.Flags().Duration("visibility-timeout", 30*time.Second, "How long a pulled event is hidden before it's redelivered")
+	serverCmd.Flags().BoolP("quiet", "q", false, "Don't log requests and deliveries")
+
+	devCmd.AddCommand(serverCmd)
+
+	rootCmd.AddCommand(devCmd)
+}
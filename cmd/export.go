@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sailhouse/sailhouse/models"
+	"github.com/sailhouse/sailhouse/schema"
+	"github.com/sailhouse/sailhouse/util/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the current app's topics and subscriptions as a schema file",
+		Long: `Export the current app's topics and subscriptions as a schema file.
+
+The schema is written to stdout, or to --output, and can be fed back into
+plan and apply. The schema key defaults to the key shared by the app's topics,
+if any.`,
+		Example: `  sailhouse export > sailhouse.yaml
+  sailhouse export --key orders --output sailhouse.yaml`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			out := output.NewOutput[models.Schema]()
+			token := viper.GetString("token")
+			app := getApp()
+
+			key, _ := cmd.Flags().GetString("key")
+			path, _ := cmd.Flags().GetString("output")
+
+			client := newClient(token)
+
+			live, err := schema.FetchLive(context.Background(), client, app)
+			if err != nil {
+				out.AddError("Failed to fetch the current topics and subscriptions", err)
+				out.Print()
+				os.Exit(out.ExitCode())
+			}
+
+			if !cmd.Flags().Changed("key") {
+				key = schema.SharedKey(live)
+			}
+
+			exported := schema.FromLive(live, key)
+
+			var exportBytes []byte
+			if viper.Get("format") == "json" {
+				exportBytes, err = json.MarshalIndent(exported, "", "  ")
+				exportBytes = append(exportBytes, '\n')
+			} else {
+				exportBytes, err = schema.Marshal(exported)
+			}
+			if err != nil {
+				out.AddError("Failed to encode schema", err)
+				out.Print()
+				os.Exit(out.ExitCode())
+			}
+
+			// Warn rather than fail, the export is still useful as a starting
+			// point even if something in the app can't be expressed cleanly.
+			if err := schema.Validate(exported); err != nil {
+				warnText := lipgloss.NewStyle().Foreground(lipgloss.Color("220")).Render(fmt.Sprintf("Warning: the exported schema will not pass validation as-is\n%s", err))
+				fmt.Fprintln(os.Stderr, warnText)
+			}
+
+			if path == "" || path == "-" {
+				os.Stdout.Write(exportBytes)
+				return
+			}
+
+			if err := os.WriteFile(path, exportBytes, 0644); err != nil {
+				out.AddError("Failed to write schema", err)
+				out.Print()
+				os.Exit(out.ExitCode())
+			}
+
+			out.SetData(exported)
+			out.AddMessage(fmt.Sprintf("Exported %d topics and %d subscriptions to %s", len(exported.Topics), len(exported.Subscriptions), path))
+			out.Print()
+		},
+	}
+
+	exportCmd.Flags().String("key", "", "Schema key to write into the file")
+	exportCmd.Flags().StringP("output", "o", "", "File to write the schema to (defaults to stdout)")
+
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sailhouse/sailhouse/config"
+	"github.com/sailhouse/sailhouse/util/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Link the current directory to an app",
+		Long: `Link the current directory to an app.
+
+Writes .sailhouse/config.yaml, which commands run in this directory or any
+directory below it pick up. Settings are resolved in this order, highest first:
+
+  1. flags (--app, --team, --api-url)
+  2. SAILHOUSE_* environment variables
+  3. the project's env overrides for the active profile
+  4. the project config
+  5. the active profile
+
+Overrides for a profile go under env, for example:
+
+  app: orders
+  team: acme
+  topic: order-created
+  schema: sailhouse.yaml
+  env:
+    staging:
+      app: orders-staging
+      api_url: https://api.staging.sailhouse.dev`,
+		Example: `  sailhouse init --app orders --topic order-created`,
+		Args:    cobra.NoArgs,
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[config.Project]) {
+			force, _ := cmd.Flags().GetBool("force")
+			topic, _ := cmd.Flags().GetString("topic")
+			schemaPath, _ := cmd.Flags().GetString("schema")
+
+			path := filepath.Join(config.ProjectDir, config.ProjectFileName)
+			if _, err := os.Stat(path); err == nil && !force {
+				out.AddError(fmt.Sprintf("%s already exists, pass --force to overwrite it", path))
+				return
+			} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+				out.AddError("Failed to check for an existing project config", err)
+				return
+			}
+
+			// Skip viper's team and app, which include a parent project's,
+			// so they aren't copied.
+			teamFlag, _ := cmd.Flags().GetString("team")
+			team := config.NewProjectTeam(teamFlag, activeProfile)
+			if team == "" {
+				out.AddError("No team selected", output.WithCode(output.CodeValidation, errors.New("pass --team, set SAILHOUSE_TEAM or run `sailhouse config set team [team]`")))
+				return
+			}
+			// selectApp lists the apps of viper's team.
+			viper.Set("team", team)
+
+			app, _ := cmd.Flags().GetString("app")
+			if app == "" {
+				app = os.Getenv("SAILHOUSE_APP")
+			}
+			if app == "" {
+				app = selectApp()
+			}
+
+			if schemaPath == "" {
+				if _, err := os.Stat("sailhouse.yaml"); err == nil {
+					schemaPath = "sailhouse.yaml"
+				}
+			}
+
+			project := config.Project{
+				App:    app,
+				Team:   team,
+				Topic:  topic,
+				Schema: schemaPath,
+				Path:   path,
+			}
+
+			if err := project.Save(); err != nil {
+				out.AddError("Failed to write project config", err)
+				return
+			}
+
+			out.SetData(project)
+			out.AddMessage(fmt.Sprintf("Linked this directory to %s/%s in %s", project.Team, project.App, path))
+		}),
+	}
+	initCmd.Flags().String("topic", "", "Default topic for publish, listen and subs list")
+	initCmd.Flags().String("schema", "", "Schema file used by plan and apply (defaults to sailhouse.yaml when present)")
+	initCmd.Flags().Bool("force", false, "Overwrite an existing project config")
+
+	rootCmd.AddCommand(initCmd)
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sailhouse/sailhouse/api"
+	"github.com/spf13/cobra"
+)
+
+const defaultListLimit = 100
+
+// addListFlags adds --limit, --page-size and --all to a list command. Read
+// them back with listOptions and listItems.
+func addListFlags(cmd *cobra.Command, noun string) {
+	cmd.Flags().IntP("limit", "l", defaultListLimit, fmt.Sprintf("Maximum number of %s to list", noun))
+	cmd.Flags().Int("page-size", 0, "Number of items to fetch per request, 0 uses the API's default")
+	cmd.Flags().Bool("all", false, fmt.Sprintf("List all %s, ignoring --limit", noun))
+	cmd.MarkFlagsMutuallyExclusive("limit", "all")
+}
+
+func listOptions(cmd *cobra.Command) api.ListOptions {
+	pageSize, _ := cmd.Flags().GetInt("page-size")
+	return api.ListOptions{PageSize: pageSize}
+}
+
+// listItems collects items from pager up to --limit, or all of them with
+// --all. more reports whether items were left out.
+func listItems[T any](ctx context.Context, cmd *cobra.Command, pager *api.Pager[T]) (items []T, more bool, err error) {
+	limit, _ := cmd.Flags().GetInt("limit")
+	all, _ := cmd.Flags().GetBool("all")
+	if !all && limit < 1 {
+		return nil, false, errors.New("--limit must be at least 1")
+	}
+
+	items = []T{}
+	err = pager.Each(ctx, func(item T) bool {
+		if !all && len(items) >= limit {
+			more = true
+			return false
+		}
+
+		items = append(items, item)
+		return true
+	})
+
+	return items, more, err
+}
+
+// moreMessage tells the user how to see the items listItems left out.
+func moreMessage(count int, noun string) string {
+	return fmt.Sprintf("Showing the first %d %s, pass --all or a higher --limit to see more", count, noun)
+}
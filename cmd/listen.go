@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sailhouse/sailhouse/api"
+	"github.com/sailhouse/sailhouse/models"
+	"github.com/sailhouse/sailhouse/publicid"
+	"github.com/sailhouse/sailhouse/util"
+	"github.com/sailhouse/sailhouse/util/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type ListenDelivery struct {
+	EventID    string `json:"event_id"`
+	StatusCode int    `json:"status_code,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	Acked      bool   `json:"acked"`
+	Error      string `json:"error,omitempty"`
+}
+
+func init() {
+	listenCmd := &cobra.Command{
+		Use:   "listen [topic]",
+		Short: "Forward events from a topic to a local endpoint",
+		Long: `Forward events from a topic to a local endpoint.
+
+A temporary pull subscription is created on the topic and every event it
+receives is POSTed as JSON to the --forward URL. Events are acknowledged when
+the endpoint responds with a 2xx status, otherwise they are left for
+redelivery. The temporary subscription is deleted when listen exits.`,
+		Example: `  sailhouse listen orders --forward http://localhost:8080/hook`,
+		Args:    cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			forwardTimeout, _ := cmd.Flags().GetDuration("forward-timeout")
+
+			// The deprecated --timeout shadows the global flag, so it sets
+			// the API timeout as well as the forwarding one.
+			if cmd.Flags().Changed("timeout") {
+				timeout, _ := cmd.Flags().GetDuration("timeout")
+				viper.Set("timeout", timeout)
+				if !cmd.Flags().Changed("forward-timeout") {
+					forwardTimeout = timeout
+				}
+			}
+
+			token := viper.GetString("token")
+			app := getApp()
+
+			forward, _ := cmd.Flags().GetString("forward")
+			filterPath, _ := cmd.Flags().GetString("filter-path")
+			filterValue, _ := cmd.Flags().GetString("filter-value")
+
+			stream := output.NewStream[ListenDelivery]()
+			defer stream.ExitOnError()
+
+			if !util.IsValidForwardEndpoint(forward) {
+				stream.Error(fmt.Sprintf("%q is not a valid http(s) URL", forward))
+				return
+			}
+
+			topic, err := getTopic(args)
+			if err != nil {
+				stream.Error(err.Error())
+				return
+			}
+
+			client := newClient(token)
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			sub, err := client.CreateSubscription(ctx, app, api.CreateSubscription{
+				Slug:        fmt.Sprintf("cli-listen-%s", publicid.Must()),
+				TopicSlug:   topic,
+				Type:        "pull",
+				FilterPath:  filterPath,
+				FilterValue: filterValue,
+			})
+			if err != nil {
+				stream.Error("Error creating temporary subscription", err)
+				return
+			}
+
+			defer func() {
+				// ctx is cancelled by now, so clean up with a fresh one.
+				cleanupCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+
+				if err := client.DeleteSubscription(cleanupCtx, app, topic, sub.Slug); err != nil {
+					stream.Error(fmt.Sprintf("Error deleting temporary subscription %s", sub.Slug), err)
+					return
+				}
+
+				stream.Message(fmt.Sprintf("Deleted temporary subscription %s", sub.Slug))
+			}()
+
+			stream.Message(fmt.Sprintf("Forwarding events from %s to %s (subscription %s)", topic, forward, sub.Slug))
+			stream.Message("Press Ctrl-C to stop")
+
+			httpClient := &http.Client{Timeout: forwardTimeout}
+
+			for {
+				events, err := client.PullEvents(ctx, app, topic, sub.Slug, api.PullEvents{
+					Limit: 10,
+					Wait:  20 * time.Second,
+				})
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+
+					stream.Error("Error pulling events", err)
+					if !sleepContext(ctx, 5*time.Second) {
+						return
+					}
+					continue
+				}
+
+				for _, event := range events {
+					delivery := forwardEvent(ctx, httpClient, forward, event)
+
+					if delivery.Error == "" {
+						if err := client.AckEvent(ctx, app, topic, sub.Slug, event.ID); err != nil {
+							delivery.Error = fmt.Sprintf("forwarded but failed to acknowledge: %s", err)
+						} else {
+							delivery.Acked = true
+						}
+					}
+
+					stream.Write(delivery, formatDelivery(delivery))
+				}
+
+				if ctx.Err() != nil {
+					return
+				}
+			}
+		},
+	}
+
+	listenCmd.Flags().String("forward", "", "Local URL to POST events to, e.g. http://localhost:8080/hook")
+	listenCmd.Flags().StringP("filter-path", "p", "", "Only forward events matching this filter path")
+	listenCmd.Flags().StringP("filter-value", "v", "", "Value the filter path must match")
+	listenCmd.Flags().Duration("forward-timeout", 10*time.Second, "Timeout for each request to the local endpoint")
+	listenCmd.Flags().Duration("timeout", 10*time.Second, "Timeout for each request to the local endpoint and the API")
+	listenCmd.Flags().MarkDeprecated("timeout", "use --forward-timeout for the local endpoint, --timeout will only set the API timeout")
+	listenCmd.MarkFlagRequired("forward")
+
+	rootCmd.AddCommand(listenCmd)
+}
+
+func forwardEvent(ctx context.Context, httpClient *http.Client, endpoint string, event models.Event) (delivery ListenDelivery) {
+	delivery = ListenDelivery{EventID: event.ID}
+	start := time.Now()
+	defer func() {
+		delivery.DurationMS = time.Since(start).Milliseconds()
+	}()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Sailhouse-Event-Id", event.ID)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	delivery.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		delivery.Error = fmt.Sprintf("endpoint responded with %s", resp.Status)
+	}
+
+	return delivery
+}
+
+func formatDelivery(delivery ListenDelivery) string {
+	timestamp := time.Now().Format(time.TimeOnly)
+
+	if delivery.Error != "" {
+		status := lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render("FAIL")
+		return fmt.Sprintf("%s %s %s %s", timestamp, status, delivery.EventID, delivery.Error)
+	}
+
+	status := lipgloss.NewStyle().Foreground(lipgloss.Color("42")).Render(fmt.Sprintf("%d", delivery.StatusCode))
+	return fmt.Sprintf("%s %s %s (%dms)", timestamp, status, delivery.EventID, delivery.DurationMS)
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sailhouse/sailhouse/schema"
+	"github.com/sailhouse/sailhouse/util/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// planDriftExitCode is returned by `sailhouse plan` when the live app doesn't
+// match the schema, so CI can tell drift apart from a failed run.
+const planDriftExitCode = output.ExitDrift
+
+var (
+	planAdd     = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	planChange  = lipgloss.NewStyle().Foreground(lipgloss.Color("220"))
+	planDestroy = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+)
+
+func init() {
+	planCmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Show the changes apply would make to the current app",
+		Long: `Show the changes apply would make to the current app.
+
+Exits with status 2 when the app has drifted from the schema, 1 on errors and
+0 when everything is up to date. Use --format json for a machine-readable plan.
+
+The schema file is passed with --file, which has no -f shorthand because -f is
+the global --format flag.`,
+		Example: `  sailhouse plan --file sailhouse.yaml
+  sailhouse plan --file sailhouse.yaml --format json`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			out := output.NewOutput[schema.Plan]()
+			token := viper.GetString("token")
+
+			file := getSchemaFile(cmd)
+			prune, _ := cmd.Flags().GetBool("prune")
+
+			desired, err := schema.Load(file)
+			if err != nil {
+				out.AddError("Failed to load schema", err)
+				out.Print()
+				os.Exit(out.ExitCode())
+			}
+
+			if err := schema.Validate(desired); err != nil {
+				out.AddError(err.Error())
+				out.Print()
+				os.Exit(out.ExitCode())
+			}
+
+			app := getApp()
+			client := newClient(token)
+
+			live, err := schema.FetchLive(context.Background(), client, app)
+			if err != nil {
+				out.AddError("Failed to fetch the current topics and subscriptions", err)
+				out.Print()
+				os.Exit(out.ExitCode())
+			}
+
+			plan := schema.Diff(desired, live, schema.DiffOptions{Prune: prune})
+			out.SetData(plan)
+
+			if !plan.HasChanges() {
+				out.AddMessage(fmt.Sprintf("No changes, %s matches %s", app, file))
+				out.Print()
+				return
+			}
+
+			for _, change := range plan.Changes {
+				out.AddMessage(renderChange(change))
+			}
+			out.AddMessage(renderPlanSummary(plan))
+			out.Print()
+
+			os.Exit(planDriftExitCode)
+		},
+	}
+
+	planCmd.Flags().String("file", "sailhouse.yaml", "Path to the schema file (defaults to the project's schema when set)")
+	planCmd.Flags().Bool("prune", false, "Include deletes for topics and subscriptions that weren't created by the schema")
+
+	rootCmd.AddCommand(planCmd)
+}
+
+func renderChangeHeader(change schema.Change) string {
+	switch change.Action {
+	case schema.ActionCreate:
+		return planAdd.Render(fmt.Sprintf("+ %s %s", change.Kind, change.Name()))
+	case schema.ActionDelete:
+		return planDestroy.Render(fmt.Sprintf("- %s %s", change.Kind, change.Name()))
+	default:
+		return planChange.Render(fmt.Sprintf("~ %s %s (%s)", change.Kind, change.Name(), change.Action))
+	}
+}
+
+// renderChange formats a change in the style of a Terraform plan, listing
+// the fields that will be set or changed underneath the resource.
+func renderChange(change schema.Change) string {
+	lines := []string{renderChangeHeader(change)}
+
+	width := 0
+	for _, field := range change.Fields {
+		if len(field.Field) > width {
+			width = len(field.Field)
+		}
+	}
+
+	for _, field := range change.Fields {
+		name := fmt.Sprintf("%-*s", width, field.Field)
+
+		if change.Action == schema.ActionCreate {
+			lines = append(lines, fmt.Sprintf("    %s = %q", name, field.To))
+			continue
+		}
+
+		lines = append(lines, planChange.Render(fmt.Sprintf("    ~ %s = %q -> %q", name, field.From, field.To)))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func renderPlanSummary(plan schema.Plan) string {
+	add, change, destroy := plan.Counts()
+	return fmt.Sprintf("\nPlan: %d to add, %d to change, %d to destroy.", add, change, destroy)
+}
@@ -1,25 +1,356 @@
 package cmd
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sailhouse/sailhouse/config"
+	"github.com/sailhouse/sailhouse/util/output"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
-func AddProfileCmd() *cobra.Command {
+type ProfileSummary struct {
+	Name        string `json:"name"`
+	Team        string `json:"team"`
+	Token       string `json:"token,omitempty"`
+	Credentials string `json:"credentials"`
+	APIURL      string `json:"api_url,omitempty"`
+	WebURL      string `json:"web_url,omitempty"`
+	Active      bool   `json:"active"`
+}
+
+func summarizeProfile(profile config.Profile, active string) ProfileSummary {
+	credentials := profile.Credentials
+	if credentials == "" {
+		credentials = config.CredentialsPlaintext
+	}
+
+	summary := ProfileSummary{
+		Name:        profile.Name,
+		Team:        profile.Team,
+		Credentials: credentials,
+		APIURL:      profile.APIURL,
+		WebURL:      profile.WebURL,
+		Active:      profile.Name == active,
+	}
+
+	// Tokens in a credential store aren't read just to be masked, that
+	// could mean a passphrase prompt.
+	if credentials == config.CredentialsPlaintext {
+		summary.Token = maskToken(profile.Token)
+	}
+
+	return summary
+}
+
+func init() {
 	profileCmd := &cobra.Command{
 		Use:   "profile",
-		Short: "Manage your profile",
-		Run: func(cmd *cobra.Command, args []string) {
-			cmd.Help()
-		},
+		Short: "Manage named profiles",
+		Long: `Manage named profiles.
+
+Each profile holds its own token, team and API URLs. The active profile is
+chosen with --profile or SAILHOUSE_PROFILE, falling back to the one selected
+with "sailhouse profile use".`,
 	}
 
 	profileCmd.AddCommand(&cobra.Command{
-		Use:   "set",
-		Short: "Set a profile value",
-		Run: func(cmd *cobra.Command, args []string) {
-			cmd.Help()
-		},
+		Use:   "list",
+		Short: "List profiles",
+		Args:  cobra.NoArgs,
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[[]ProfileSummary]) {
+			file, err := config.LoadProfileFile()
+			if err != nil {
+				out.AddError("Failed to load profiles", err)
+				return
+			}
+			active := viper.GetString("profile")
+
+			profiles := []ProfileSummary{}
+			table := output.NewTable()
+			table.AddColumns("", "Name", "Team", "Credentials", "API")
+
+			for _, name := range file.Names() {
+				summary := summarizeProfile(file.Profiles[name], active)
+				profiles = append(profiles, summary)
+
+				marker := ""
+				if summary.Active {
+					marker = "*"
+					name = lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render(name)
+				}
+				table.AddRow(marker, name, summary.Team, summary.Credentials, summary.APIURL)
+			}
+
+			out.SetData(profiles)
+
+			if len(profiles) == 0 {
+				out.AddMessage("No profiles found, run `sailhouse auth login` to create one")
+				return
+			}
+
+			out.SetTable(table)
+		}),
+	})
+
+	profileCmd.AddCommand(&cobra.Command{
+		Use:   "use [name]",
+		Short: "Switch the active profile",
+		Args:  cobra.ExactArgs(1),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[ProfileSummary]) {
+			file, err := config.LoadProfileFile()
+			if err != nil {
+				out.AddError("Failed to load profiles", err)
+				return
+			}
+
+			if err := file.Use(args[0]); err != nil {
+				out.AddError(fmt.Sprintf("%s, create it with `sailhouse profile create %s`", err, args[0]))
+				return
+			}
+
+			if err := file.Save(); err != nil {
+				out.AddError("Failed to save profiles", err)
+				return
+			}
+
+			out.SetData(summarizeProfile(file.Profiles[args[0]], args[0]))
+			out.AddMessage(fmt.Sprintf("Now using profile %s", args[0]))
+		}),
 	})
 
-	return profileCmd
+	createCmd := &cobra.Command{
+		Use:   "create [name]",
+		Short: "Create a profile",
+		Example: `  sailhouse profile create staging --team acme --api-url https://api.staging.sailhouse.dev
+  sailhouse --profile staging auth login`,
+		Args: cobra.ExactArgs(1),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[ProfileSummary]) {
+			name := args[0]
+			file, err := config.LoadProfileFile()
+			if err != nil {
+				out.AddError("Failed to load profiles", err)
+				return
+			}
+
+			if _, ok := file.Profiles[name]; ok {
+				out.AddError(fmt.Sprintf("Profile %s already exists", name))
+				return
+			}
+
+			// --team and --api-url are the global flags, read directly so
+			// the values don't fall back to the active profile's.
+			profile := config.Profile{Name: name}
+			profile.Team, _ = cmd.Flags().GetString("team")
+			profile.APIURL, _ = cmd.Flags().GetString("api-url")
+			profile.WebURL, _ = cmd.Flags().GetString("web-url")
+
+			file.Profiles[name] = profile
+
+			use, _ := cmd.Flags().GetBool("use")
+			if use {
+				file.Current = name
+			}
+
+			if err := file.Save(); err != nil {
+				out.AddError("Failed to save profiles", err)
+				return
+			}
+
+			out.SetData(summarizeProfile(profile, file.ActiveName("")))
+			out.AddMessage(fmt.Sprintf("Created profile %s", name))
+			if use {
+				out.AddMessage(fmt.Sprintf("Now using profile %s", name))
+			}
+			out.AddMessage(fmt.Sprintf("Run `sailhouse --profile %s auth login` to sign in", name))
+		}),
+	}
+	createCmd.Flags().String("web-url", "", "Web URL for the new profile")
+	createCmd.Flags().Bool("use", false, "Switch to the new profile")
+
+	profileCmd.AddCommand(createCmd)
+
+	profileCmd.AddCommand(&cobra.Command{
+		Use:   "delete [name]",
+		Short: "Delete a profile",
+		Args:  cobra.ExactArgs(1),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[string]) {
+			file, err := config.LoadProfileFile()
+			if err != nil {
+				out.AddError("Failed to load profiles", err)
+				return
+			}
+
+			profile, ok := file.Profiles[args[0]]
+			if !ok {
+				out.AddCodedError(output.CodeNotFound, fmt.Sprintf("Profile %s does not exist", args[0]))
+				return
+			}
+
+			if err := profile.DeleteToken(); err != nil {
+				out.AddError("Failed to remove the profile's token from its credential store", err)
+				return
+			}
+
+			file.Delete(args[0])
+
+			if err := file.Save(); err != nil {
+				out.AddError("Failed to save profiles", err)
+				return
+			}
+
+			out.SetData(args[0])
+			out.AddMessage(fmt.Sprintf("Deleted profile %s", args[0]))
+		}),
+	})
+
+	profileCmd.AddCommand(&cobra.Command{
+		Use:   "show [name]",
+		Short: "Show a profile, defaulting to the active one",
+		Args:  cobra.MaximumNArgs(1),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[ProfileSummary]) {
+			file, err := config.LoadProfileFile()
+			if err != nil {
+				out.AddError("Failed to load profiles", err)
+				return
+			}
+			active := viper.GetString("profile")
+
+			name := active
+			if len(args) == 1 {
+				name = args[0]
+			}
+
+			profile, ok := file.Profiles[name]
+			if !ok {
+				out.AddCodedError(output.CodeNotFound, fmt.Sprintf("Profile %s does not exist", name))
+				return
+			}
+
+			summary := summarizeProfile(profile, active)
+			out.SetData(summary)
+
+			out.AddMessage(fmt.Sprintf("Profile: %s", summary.Name))
+			if summary.Token != "" {
+				out.AddMessage(fmt.Sprintf("Token: %s", summary.Token))
+			}
+			out.AddMessage(fmt.Sprintf("Credentials: %s", summary.Credentials))
+			out.AddMessage(fmt.Sprintf("Team: %s", summary.Team))
+			if summary.APIURL != "" {
+				out.AddMessage(fmt.Sprintf("API: %s", summary.APIURL))
+			}
+			if summary.WebURL != "" {
+				out.AddMessage(fmt.Sprintf("Web: %s", summary.WebURL))
+			}
+		}),
+	})
+
+	migrateCmd := &cobra.Command{
+		Use:   "migrate-credentials",
+		Short: "Move tokens out of profile.toml into a credential store",
+		Long: `Move tokens out of profile.toml into a credential store.
+
+Plaintext tokens are moved into the OS keyring by default, or into a
+passphrase-encrypted file with --store file. Tokens already in another store
+are moved too. Only the active profile is migrated unless --all is passed.`,
+		Args: cobra.NoArgs,
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[[]ProfileSummary]) {
+			store, _ := cmd.Flags().GetString("store")
+			all, _ := cmd.Flags().GetBool("all")
+
+			if store == "" {
+				store = config.DefaultCredentialStore()
+			}
+
+			file, err := config.LoadProfileFile()
+			if err != nil {
+				out.AddError("Failed to load profiles", err)
+				return
+			}
+
+			names := []string{viper.GetString("profile")}
+			if all {
+				names = file.Names()
+			}
+
+			migrated := []ProfileSummary{}
+			for _, name := range names {
+				profile, ok := file.Profiles[name]
+				if !ok {
+					out.AddCodedError(output.CodeNotFound, fmt.Sprintf("Profile %s does not exist", name))
+					continue
+				}
+
+				current := profile.Credentials
+				if current == "" {
+					current = config.CredentialsPlaintext
+				}
+
+				if current == store {
+					out.AddMessage(fmt.Sprintf("Profile %s already uses the %s store", name, store))
+					continue
+				}
+
+				token, err := profile.LoadToken()
+				if err != nil {
+					out.AddError(fmt.Sprintf("Failed to read the token for profile %s", name), err)
+					continue
+				}
+
+				if token == "" {
+					out.AddMessage(fmt.Sprintf("Profile %s has no token, skipping", name))
+					continue
+				}
+
+				if err := profile.StoreToken(store, token); err != nil {
+					out.AddError(fmt.Sprintf("Failed to store the token for profile %s", name), err)
+					continue
+				}
+
+				// Remove the token from the old store only once it's safely
+				// in the new one.
+				old := config.Profile{Name: name, Credentials: current}
+				if current != config.CredentialsPlaintext {
+					if err := old.DeleteToken(); err != nil {
+						out.AddError(fmt.Sprintf("Failed to remove the old token for profile %s from the %s store", name, current), err)
+					}
+				}
+
+				file.Profiles[name] = profile
+				// Save after each profile so a later failure doesn't lose
+				// track of tokens that were already moved.
+				if err := file.Save(); err != nil {
+					out.AddError("Failed to save profiles", err)
+					return
+				}
+
+				migrated = append(migrated, summarizeProfile(profile, viper.GetString("profile")))
+				out.AddMessage(fmt.Sprintf("Moved the token for profile %s from %s to %s", name, current, store))
+			}
+
+			out.SetData(migrated)
+		}),
+	}
+	migrateCmd.Flags().String("store", "", "Credential store to move tokens into [keyring | file | plaintext] (defaults to keyring when available)")
+	migrateCmd.Flags().Bool("all", false, "Migrate every profile instead of just the active one")
+
+	profileCmd.AddCommand(migrateCmd)
+
+	rootCmd.AddCommand(profileCmd)
+}
+
+// maskToken hides all but the start of a token so it can be shown safely.
+func maskToken(token string) string {
+	if token == "" {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render("Not set")
+	}
+
+	visible := 12
+	if len(token) <= visible {
+		visible = len(token) / 2
+	}
+
+	return token[:visible] + strings.Repeat("*", len(token)-visible)
 }
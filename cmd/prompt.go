@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/viper"
+)
+
+// inputAvailable reports whether the CLI can prompt. Prompting is off when
+// --no-input is passed, stdin isn't a terminal, or CI=true, so pipelines fail
+// fast instead of hanging on a prompt nobody can answer.
+func inputAvailable() bool {
+	return !viper.GetBool("no_input")
+}
+
+func defaultNoInput() bool {
+	if ci := os.Getenv("CI"); ci == "true" || ci == "1" {
+		return true
+	}
+
+	fd := os.Stdin.Fd()
+	return !isatty.IsTerminal(fd) && !isatty.IsCygwinTerminal(fd)
+}
+
+// NoInputError is returned in place of a prompt when input is disabled. It
+// names what to pass instead.
+type NoInputError struct {
+	Missing string
+}
+
+func (e *NoInputError) Error() string {
+	return fmt.Sprintf("input is disabled (--no-input, CI or no terminal), pass %s", e.Missing)
+}
+
+func (e *NoInputError) ErrorCode() string { return "no_input" }
+func (e *NoInputError) ErrorDetails() any { return map[string]string{"missing": e.Missing} }
+
+// ask runs a survey prompt, or fails with a NoInputError naming missing when
+// prompting isn't possible.
+func ask(prompt survey.Prompt, response any, missing string, opts ...survey.AskOpt) error {
+	if !inputAvailable() {
+		return &NoInputError{Missing: missing}
+	}
+
+	return survey.AskOne(prompt, response, opts...)
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sailhouse/sailhouse/api"
+	"github.com/sailhouse/sailhouse/util/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	publishCmd := &cobra.Command{
+		Use:   "publish [topic] [payload]",
+		Short: "Publish an event to a topic",
+		Long: `Publish an event to a topic.
+
+The JSON payload can be passed as an argument, read from a file with --file,
+or piped through stdin (pass "-" as the payload to read stdin explicitly).
+The topic defaults to the project's topic when one is set.`,
+		Example: `  sailhouse publish orders '{"id": 42}'
+  sailhouse publish orders --file event.json --metadata source=cli
+  echo '{"id": 42}' | sailhouse publish orders --send-at 10m`,
+		Args: cobra.RangeArgs(0, 2),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[api.PublishEventResponse]) {
+			token := viper.GetString("token")
+			app := getApp()
+
+			file, _ := cmd.Flags().GetString("file")
+			metadata, _ := cmd.Flags().GetStringToString("metadata")
+			sendAtFlag, _ := cmd.Flags().GetString("send-at")
+			idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+
+			// A lone JSON argument is the payload for the project's default
+			// topic, slugs can't start with { or [.
+			topicArgs, payloadArgs := args, []string{}
+			if len(args) == 1 && (strings.HasPrefix(args[0], "{") || strings.HasPrefix(args[0], "[")) {
+				topicArgs, payloadArgs = nil, args
+			} else if len(args) > 1 {
+				payloadArgs = args[1:]
+			}
+
+			topic, err := getTopic(topicArgs)
+			if err != nil {
+				out.AddError(err.Error())
+				return
+			}
+
+			payload, err := readPayload(payloadArgs, file)
+			if err != nil {
+				out.AddError("Failed to read payload", err)
+				return
+			}
+
+			if !json.Valid(payload) {
+				out.AddCodedError(output.CodeValidation, "Payload must be valid JSON")
+				return
+			}
+
+			var sendAt *time.Time
+			if sendAtFlag != "" {
+				t, err := parseSendAt(sendAtFlag)
+				if err != nil {
+					out.AddError("Invalid --send-at value", err)
+					return
+				}
+				sendAt = &t
+			}
+
+			client := newClient(token)
+
+			resp, err := client.PublishEvent(context.Background(), app, api.PublishEvent{
+				TopicSlug:      topic,
+				Data:           payload,
+				Metadata:       metadata,
+				SendAt:         sendAt,
+				IdempotencyKey: idempotencyKey,
+			})
+			if err != nil {
+				out.AddError("Failed to publish event", err)
+				return
+			}
+
+			out.SetData(resp)
+			if sendAt != nil {
+				out.AddMessage(fmt.Sprintf("Scheduled event %s on %s for %s", resp.ID, topic, sendAt.Format(time.RFC3339)))
+			} else {
+				out.AddMessage(fmt.Sprintf("Published event %s to %s", resp.ID, topic))
+			}
+		}),
+	}
+
+	publishCmd.Flags().String("file", "", "Read the JSON payload from a file")
+	publishCmd.Flags().StringToStringP("metadata", "m", nil, "Metadata to attach to the event (key=value)")
+	publishCmd.Flags().String("send-at", "", "Schedule delivery, as an RFC3339 time or a duration from now (e.g. 10m)")
+	publishCmd.Flags().String("idempotency-key", "", "Key used by Sailhouse to deduplicate repeated publishes (random by default, so retries are only published once)")
+
+	rootCmd.AddCommand(publishCmd)
+}
+
+// readPayload resolves the event payload from, in order, the positional
+// argument, the --file flag, or stdin.
+func readPayload(args []string, file string) ([]byte, error) {
+	if len(args) > 0 && args[0] != "-" {
+		if file != "" {
+			return nil, errors.New("pass either a payload argument or --file, not both")
+		}
+		return []byte(args[0]), nil
+	}
+
+	if file != "" {
+		return os.ReadFile(file)
+	}
+
+	if len(args) == 0 && !stdinIsPiped() {
+		return nil, errors.New("no payload given, pass it as an argument, with --file or via stdin")
+	}
+
+	return io.ReadAll(os.Stdin)
+}
+
+func stdinIsPiped() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+
+	return stat.Mode()&os.ModeCharDevice == 0
+}
+
+func parseSendAt(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC3339 time nor a duration", value)
+	}
+
+	if d <= 0 {
+		return time.Time{}, errors.New("duration must be positive")
+	}
+
+	return time.Now().Add(d), nil
+}
@@ -2,12 +2,11 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"os/user"
-	"path"
 	"strings"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/carlmjohnson/requests"
@@ -15,31 +14,70 @@ import (
 	"github.com/getsentry/sentry-go"
 	"github.com/hashicorp/go-version"
 	"github.com/sailhouse/sailhouse/api"
+	"github.com/sailhouse/sailhouse/config"
+	"github.com/sailhouse/sailhouse/util/output"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"gopkg.in/yaml.v3"
 )
 
 var rootCmd = &cobra.Command{Use: "sailhouse", PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	// -f is --format, so a mistyped -f schema.yaml must fail rather than
+	// leave --file at its default.
+	if format := viper.GetString("format"); format != "json" && format != "text" {
+		err := fmt.Errorf("--format must be json or text, got %q", format)
+		if cmd.Flags().Lookup("file") != nil {
+			err = fmt.Errorf("%w, -f is short for --format, pass files with --file", err)
+		}
+		exitWithError("Invalid format", output.WithCode(output.CodeValidation, err))
+	}
+
 	if viper.Get("format") != "json" {
 		checkVersion(viper.GetString("version"))
 	}
 
+	if !hasCommandPrefix(cmd, "sailhouse auth", "sailhouse config", "sailhouse dev", "sailhouse profile") {
+		loadToken()
+	}
+
 	team := viper.Get("team")
 	if team == "" {
-		path := cmd.CommandPath()
-		if !strings.HasPrefix(path, "sailhouse teams") && !strings.HasPrefix(path, "sailhouse auth") {
-			fmt.Println("Please set your team with `sailhouse config set team [team]`")
-			os.Exit(1)
+		if !hasCommandPrefix(cmd, "sailhouse teams", "sailhouse auth", "sailhouse config", "sailhouse dev", "sailhouse profile") {
+			exitWithError("No team selected", output.WithCode(output.CodeValidation, errors.New("set one with `sailhouse config set team [team]`, --team or SAILHOUSE_TEAM")))
 		}
 	}
 },
 }
 
+func hasCommandPrefix(cmd *cobra.Command, prefixes ...string) bool {
+	path := cmd.CommandPath()
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
 var configFile string
 var app string
 var format string
 var team string
+var apiURL string
+var profileName string
+var noInput bool
+var timeout time.Duration
+var retries int
+
+// activeProfile is the profile selected by --profile, SAILHOUSE_PROFILE or
+// `sailhouse profile use`, loaded before any command runs.
+var activeProfile config.Profile
+
+// activeProject is the .sailhouse/config.yaml found in the working directory
+// or one of its parents, nil outside a project.
+var activeProject *config.Project
+
+const defaultWebURL = "https://app.sailhouse.dev"
 
 type Release struct {
 	Name string `json:"name"`
@@ -52,34 +90,176 @@ func Execute(version string) {
 	rootCmd.PersistentFlags().StringVar(&app, "app", "", "App to use")
 	rootCmd.PersistentFlags().StringVar(&team, "team", "", "Team to use")
 	rootCmd.PersistentFlags().StringVarP(&format, "format", "f", "text", "Format to use [json | text]")
+	rootCmd.PersistentFlags().StringVar(&apiURL, "api-url", "", "Base URL of the Sailhouse API")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Profile to use")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", api.DefaultTimeout, "Timeout for each API request attempt, 0 to disable")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", api.DefaultRetryPolicy().MaxRetries, "Times to retry failed API requests, 0 to disable")
+	rootCmd.PersistentFlags().BoolVar(&noInput, "no-input", false, "Never prompt, fail instead (default when stdin isn't a terminal or CI=true)")
 	viper.BindPFlag("app", rootCmd.PersistentFlags().Lookup("app"))
 	viper.BindPFlag("config", rootCmd.PersistentFlags().Lookup("config"))
 	viper.BindPFlag("format", rootCmd.PersistentFlags().Lookup("format"))
 	viper.BindPFlag("team", rootCmd.PersistentFlags().Lookup("team"))
+	viper.BindPFlag("api_url", rootCmd.PersistentFlags().Lookup("api-url"))
+	viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
+	viper.BindPFlag("no_input", rootCmd.PersistentFlags().Lookup("no-input"))
+	viper.BindPFlag("timeout", rootCmd.PersistentFlags().Lookup("timeout"))
+	viper.BindPFlag("retries", rootCmd.PersistentFlags().Lookup("retries"))
+	viper.BindEnv("profile", "SAILHOUSE_PROFILE")
+	viper.BindEnv("token", "SAILHOUSE_TOKEN")
+	viper.BindEnv("team", "SAILHOUSE_TEAM")
+	viper.BindEnv("app", "SAILHOUSE_APP")
+	viper.BindEnv("no_input", "SAILHOUSE_NO_INPUT")
+	viper.BindEnv("timeout", "SAILHOUSE_TIMEOUT")
+	viper.BindEnv("retries", "SAILHOUSE_RETRIES")
+	viper.BindEnv("api_url", "SAILHOUSE_API_URL")
+	viper.BindEnv("web_url", "SAILHOUSE_WEB_URL")
+	viper.SetDefault("api_url", api.DefaultBaseURL)
+	viper.SetDefault("web_url", defaultWebURL)
+	viper.SetDefault("no_input", defaultNoInput())
+
+	config.PassphraseFunc = promptPassphrase
+	cobra.OnInitialize(loadProfile, loadProject)
 
-	usr, _ := user.Current()
-	dir := usr.HomeDir
-	configPath := path.Join(dir, ".sailhouse")
+	err := rootCmd.Execute()
+	if err != nil {
+		sentry.CaptureException(err)
+		// cobra has already printed the error.
+		os.Exit(output.NewError("", err).ExitCode())
+	}
+}
 
-	viper.SetConfigName("profile")
-	viper.SetConfigType("toml")
-	viper.AddConfigPath(configPath)
+// loadProfile makes the active profile's settings available through viper.
+// They're registered as defaults so flags and environment variables still
+// take precedence.
+func loadProfile() {
+	profile, err := config.LoadProfile(viper.GetString("profile"))
+	if err != nil {
+		exitWithError("Failed to load profile", err)
+	}
 
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+	activeProfile = profile
+	viper.Set("profile", profile.Name)
+
+	viper.SetDefault("team", profile.Team)
+
+	if profile.APIURL != "" {
+		viper.SetDefault("api_url", profile.APIURL)
+	}
+
+	if profile.WebURL != "" {
+		viper.SetDefault("web_url", profile.WebURL)
+	}
+}
+
+// loadProject layers the project config over the active profile. Settings are
+// resolved in this order, highest first:
+//
+//  1. flags (--app, --team, --api-url)
+//  2. SAILHOUSE_* environment variables
+//  3. the project's env overrides for the active profile
+//  4. the project config
+//  5. the active profile
+//  6. built-in defaults
+func loadProject() {
+	project, err := config.FindProject(".")
+	if err != nil {
+		warnText := lipgloss.NewStyle().Foreground(lipgloss.Color("220")).Render(fmt.Sprintf("Ignoring project config: %s", err))
+		fmt.Fprintln(os.Stderr, warnText)
+		return
+	}
+
+	if project == nil {
+		return
+	}
+
+	activeProject = project
+	resolved := project.Resolve(viper.GetString("profile"))
+
+	// Project settings go in viper's config layer, which sits between
+	// environment variables and the profile defaults.
+	settings := map[string]any{}
+	if resolved.App != "" {
+		settings["app"] = resolved.App
+	}
+	if resolved.Team != "" {
+		settings["team"] = resolved.Team
+	}
+	if resolved.Topic != "" {
+		settings["topic"] = resolved.Topic
+	}
+	if resolved.APIURL != "" {
+		settings["api_url"] = resolved.APIURL
+	}
+	if schemaPath := project.SchemaPath(); schemaPath != "" {
+		settings["schema"] = schemaPath
+	}
 
-		} else {
-			sentry.CaptureException(err)
-			fmt.Println("Failed to read the config file")
+	viper.MergeConfigMap(settings)
+}
+
+// getTopic returns the topic argument, falling back to the project's default
+// topic.
+func getTopic(args []string) (string, error) {
+	if len(args) > 0 && args[0] != "" {
+		return args[0], nil
+	}
+
+	if topic := viper.GetString("topic"); topic != "" {
+		return topic, nil
+	}
+
+	return "", errors.New("pass a topic, or set a default topic in .sailhouse/config.yaml")
+}
+
+// getSchemaFile returns the --file flag, falling back to the project's schema
+// path when the flag isn't set.
+func getSchemaFile(cmd *cobra.Command) string {
+	file, _ := cmd.Flags().GetString("file")
+	if !cmd.Flags().Changed("file") {
+		if schemaPath := viper.GetString("schema"); schemaPath != "" {
+			return schemaPath
 		}
 	}
 
-	err := rootCmd.Execute()
+	return file
+}
+
+// loadToken reads the active profile's token from its credential store. It
+// runs lazily, only for commands that need a token, since the encrypted file
+// store prompts for a passphrase.
+func loadToken() {
+	if viper.GetString("token") != "" {
+		return
+	}
+
+	token, err := activeProfile.LoadToken()
 	if err != nil {
-		sentry.CaptureException(err)
+		if !errors.Is(err, config.ErrCredentialNotFound) {
+			warnText := lipgloss.NewStyle().Foreground(lipgloss.Color("220")).Render(fmt.Sprintf("Failed to load credentials for profile %s: %s", activeProfile.Name, err))
+			fmt.Fprintln(os.Stderr, warnText)
+		}
+		return
+	}
+
+	viper.SetDefault("token", token)
+}
+
+func promptPassphrase() (string, error) {
+	if passphrase := os.Getenv("SAILHOUSE_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
 	}
+
+	var passphrase string
+	err := ask(&survey.Password{
+		Message: "Passphrase for your Sailhouse credentials:",
+		Help:    "Set SAILHOUSE_PASSPHRASE to avoid this prompt",
+	}, &passphrase, "the passphrase through SAILHOUSE_PASSPHRASE")
+
+	return passphrase, err
 }
 
+// checkVersion writes to stderr so it never mixes into data commands write
+// to stdout, like `sailhouse export > sailhouse.yaml`.
 func checkVersion(ver string) {
 	if ver == "v0.0.0" {
 		return
@@ -89,79 +269,79 @@ func checkVersion(ver string) {
 	var release Release
 	err := requests.URL("https://api.github.com/repos/sailhouse/cli/releases/latest").ToJSON(&release).Fetch(ctx)
 	if err != nil {
-		fmt.Println("Error fetching latest release", err)
+		fmt.Fprintln(os.Stderr, "Error fetching latest release", err)
 		return
 	}
 
 	latestVersion, err := version.NewVersion(release.Name)
 	if err != nil {
-		fmt.Println("Error parsing version", err)
+		fmt.Fprintln(os.Stderr, "Error parsing version", err)
 		return
 	}
 	currentVersion, err := version.NewVersion(ver)
 	if err != nil {
-		fmt.Println("Error parsing version", err)
+		fmt.Fprintln(os.Stderr, "Error parsing version", err)
 		return
 	}
 
 	if latestVersion.GreaterThan(currentVersion) {
 		yellowUpgradeText := lipgloss.NewStyle().Foreground(lipgloss.Color("220"))
-		fmt.Println(yellowUpgradeText.Render("A new version of sailhouse is available. Please update."))
-		fmt.Printf("%s -> %s\n\n", currentVersion, latestVersion)
+		fmt.Fprintln(os.Stderr, yellowUpgradeText.Render("A new version of sailhouse is available. Please update."))
+		fmt.Fprintf(os.Stderr, "%s -> %s\n\n", currentVersion, latestVersion)
 
-		fmt.Println("brew upgrade sailhouse")
+		fmt.Fprintln(os.Stderr, "brew upgrade sailhouse")
 
-		fmt.Print("--\n\n")
+		fmt.Fprint(os.Stderr, "--\n\n")
 	}
 }
 
 func getApp() string {
-	selectedApp := viper.GetString("app")
-	if selectedApp == "" {
-		if _, err := os.Stat("./.sailhouse/config.yaml"); err == nil {
-			type conf struct {
-				App string `yaml:"app"`
-			}
-
-			var c conf
-			yamlFile, err := ioutil.ReadFile("./.sailhouse/config.yaml")
-			if err != nil {
-				panic(err)
-			}
-
-			err = yaml.Unmarshal(yamlFile, &c)
-			if err != nil {
-				panic(err)
-			}
-		}
+	if selectedApp := viper.GetString("app"); selectedApp != "" {
+		return selectedApp
+	}
 
-		client := api.NewSailhouseClient(viper.GetString("token"))
+	return selectApp()
+}
 
-		apps, err := client.GetApps(context.Background())
-		if err != nil {
-			panic(err)
-		}
+// selectApp picks the team's only app, or asks which to use.
+func selectApp() string {
+	client := newClient(viper.GetString("token"))
 
-		if len(apps) == 0 {
-			fmt.Println("No apps found")
-			return ""
-		}
+	apps, err := client.GetApps(context.Background())
+	if err != nil {
+		exitWithError("Failed to get apps", err)
+	}
 
-		if len(apps) == 1 {
-			return apps[0].Slug
-		}
+	if len(apps) == 0 {
+		exitWithError("No apps found", output.WithCode(output.CodeNotFound, errors.New("create one with `sailhouse apps create`")))
+	}
 
-		appNames := []string{}
-		for _, app := range apps {
-			appNames = append(appNames, app.Slug)
-		}
+	if len(apps) == 1 {
+		return apps[0].Slug
+	}
 
-		prompt := &survey.Select{
-			Message: "Select an app:",
-			Options: appNames,
-		}
-		survey.AskOne(prompt, &selectedApp)
+	appNames := []string{}
+	for _, app := range apps {
+		appNames = append(appNames, app.Slug)
+	}
+
+	var selectedApp string
+	prompt := &survey.Select{
+		Message: "Select an app:",
+		Options: appNames,
+	}
+	if err := ask(prompt, &selectedApp, "--app, set SAILHOUSE_APP or run `sailhouse init`"); err != nil {
+		exitWithError("No app selected", err)
 	}
 
 	return selectedApp
 }
+
+// exitWithError reports an error outside of a command's own output, e.g.
+// while resolving the app, and exits with the error's status.
+func exitWithError(message string, err ...error) {
+	out := output.NewOutput[any]()
+	out.AddError(message, err...)
+	out.Print()
+	os.Exit(out.ExitCode())
+}
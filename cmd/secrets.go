@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	envVarPattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	k8sNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+	// Values made only of these are written to dotenv files unquoted.
+	plainEnvValue = regexp.MustCompile(`^[A-Za-z0-9_\-.:/+=@]*$`)
+)
+
+func validateEnvVar(name string) error {
+	if !envVarPattern.MatchString(name) {
+		return fmt.Errorf("invalid --env-var %q, use letters, digits and underscores, not starting with a digit", name)
+	}
+
+	return nil
+}
+
+func validateK8sName(flag, name string) error {
+	if len(name) > 253 || !k8sNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid %s %q, use lowercase letters, digits, '-' and '.'", flag, name)
+	}
+
+	return nil
+}
+
+// upsertEnvFile sets key to value in the dotenv file at path, replacing any
+// existing assignment and leaving the rest of the file alone. New files are
+// only readable by the current user.
+func upsertEnvFile(path, key, value string) error {
+	if !plainEnvValue.MatchString(value) {
+		value = strconv.Quote(value)
+	}
+
+	mode := fs.FileMode(0600)
+	contents, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	lines := []string{}
+	if len(contents) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(contents), "\n"), "\n")
+	}
+
+	found := false
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		prefix := ""
+		if rest, ok := strings.CutPrefix(trimmed, "export "); ok {
+			prefix = "export "
+			trimmed = strings.TrimLeft(rest, " \t")
+		}
+
+		name, _, ok := strings.Cut(trimmed, "=")
+		if !ok || strings.TrimSpace(name) != key {
+			continue
+		}
+
+		lines[i] = prefix + key + "=" + value
+		found = true
+	}
+
+	if !found {
+		lines = append(lines, key+"="+value)
+	}
+
+	return writeFileAtomic(path, []byte(strings.Join(lines, "\n")+"\n"), mode)
+}
+
+// writeFileAtomic replaces path in one step so a failed write can't leave a
+// half written file behind.
+func writeFileAtomic(path string, data []byte, mode fs.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+type k8sSecret struct {
+	APIVersion string            `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string            `json:"kind" yaml:"kind"`
+	Metadata   k8sMetadata       `json:"metadata" yaml:"metadata"`
+	Type       string            `json:"type" yaml:"type"`
+	StringData map[string]string `json:"stringData" yaml:"stringData"`
+}
+
+type k8sMetadata struct {
+	Name      string `json:"name" yaml:"name"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+}
+
+// newK8sSecret builds an Opaque Secret holding value under key.
+func newK8sSecret(name, namespace, key, value string) k8sSecret {
+	return k8sSecret{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata:   k8sMetadata{Name: name, Namespace: namespace},
+		Type:       "Opaque",
+		StringData: map[string]string{key: value},
+	}
+}
+
+// manifest renders the secret as JSON when the output format is json and
+// YAML otherwise; kubectl accepts either.
+func (s k8sSecret) manifest(asJSON bool) ([]byte, error) {
+	if asJSON {
+		manifest, err := json.MarshalIndent(s, "", "  ")
+		return append(manifest, '\n'), err
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(s); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), encoder.Close()
+}
+
+// pipeToCommand runs command through the shell with input on its stdin. The
+// command's output goes to stderr so it can't be mistaken for the CLI's own
+// output, e.g. when using --format json.
+func pipeToCommand(command string, input []byte) error {
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.Command("cmd", "/C", command)
+	} else {
+		c = exec.Command("sh", "-c", command)
+	}
+
+	c.Stdin = bytes.NewReader(input)
+	c.Stdout = os.Stderr
+	c.Stderr = os.Stderr
+
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("running %q: %w", command, err)
+	}
+
+	return nil
+}
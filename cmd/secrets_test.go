@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpsertEnvFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		value    string
+		want     string
+	}{
+		{"new file", "", "sh_app_1", "SAILHOUSE_TOKEN=sh_app_1\n"},
+		{"appends", "FOO=1", "sh_app_1", "FOO=1\nSAILHOUSE_TOKEN=sh_app_1\n"},
+		{"replaces", "FOO=1\nSAILHOUSE_TOKEN=old\n# comment\n", "sh_app_1", "FOO=1\nSAILHOUSE_TOKEN=sh_app_1\n# comment\n"},
+		{"keeps export", "export SAILHOUSE_TOKEN=old\n", "sh_app_1", "export SAILHOUSE_TOKEN=sh_app_1\n"},
+		{"replaces duplicates", "SAILHOUSE_TOKEN=a\nSAILHOUSE_TOKEN = b\n", "sh_app_1", "SAILHOUSE_TOKEN=sh_app_1\nSAILHOUSE_TOKEN=sh_app_1\n"},
+		{"leaves similar names", "SAILHOUSE_TOKEN_OLD=a\n", "sh_app_1", "SAILHOUSE_TOKEN_OLD=a\nSAILHOUSE_TOKEN=sh_app_1\n"},
+		{"quotes", "", "a b#c", "SAILHOUSE_TOKEN=\"a b#c\"\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			if tt.existing != "" {
+				if err := os.WriteFile(path, []byte(tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := upsertEnvFile(path, "SAILHOUSE_TOKEN", tt.value); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantPerm := os.FileMode(0600)
+			if tt.existing != "" {
+				wantPerm = 0644
+			}
+			if perm := info.Mode().Perm(); perm != wantPerm {
+				t.Errorf("file mode = %o, want %o", perm, wantPerm)
+			}
+		})
+	}
+}
@@ -3,7 +3,7 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/charmbracelet/lipgloss"
+	"github.com/sailhouse/sailhouse/api"
 	"github.com/sailhouse/sailhouse/util/output"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -17,25 +17,37 @@ func init() {
 			token := viper.GetString("token")
 			team := viper.GetString("team")
 
-			if token != "" {
-				asterixes := ""
-				for i := 0; i < len(token[12:]); i++ {
-					asterixes += "*"
-				}
-
-				token = fmt.Sprintf("%s%s", token[:12], asterixes)
-			} else {
-				warnText := lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render("Not set")
-				token = warnText
-			}
+			token = maskToken(token)
 
 			output := output.NewOutput[map[string]string]()
 
+			output.AddMessage(fmt.Sprintf("Profile: %s", viper.GetString("profile")))
 			output.AddMessage(fmt.Sprintf("Token: %s", token))
 			output.AddMessage(fmt.Sprintf("Team: %s", team))
+
+			app := viper.GetString("app")
+			if app != "" {
+				output.AddMessage(fmt.Sprintf("App: %s", app))
+			}
+
+			project := ""
+			if activeProject != nil {
+				project = activeProject.Path
+				output.AddMessage(fmt.Sprintf("Project: %s", project))
+			}
+
+			apiURL := viper.GetString("api_url")
+			if apiURL != api.DefaultBaseURL {
+				output.AddMessage(fmt.Sprintf("API: %s", apiURL))
+			}
+
 			output.SetData(map[string]string{
-				"token": token,
-				"team":  team,
+				"profile": viper.GetString("profile"),
+				"token":   token,
+				"team":    team,
+				"app":     app,
+				"project": project,
+				"api_url": apiURL,
 			})
 
 			output.Print()
@@ -4,9 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
-	"github.com/carlmjohnson/requests"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/sailhouse/sailhouse/api"
 	"github.com/sailhouse/sailhouse/models"
@@ -22,35 +26,31 @@ func init() {
 		Short: "Manage subscriptions",
 	}
 
-	subCommand.AddCommand(&cobra.Command{
+	listCmd := &cobra.Command{
 		Use:   "list [topic]",
 		Short: "List subscriptions",
-		Args:  cobra.ExactArgs(1),
+		Args:  cobra.MaximumNArgs(1),
 		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[[]models.Subscription]) {
 			token := viper.GetString("token")
 			app := getApp()
 
-			topic := args[0]
-			client := api.NewSailhouseClient(token)
-
-			topics, err := client.GetTopics(context.Background(), app)
+			topic, err := getTopic(args)
 			if err != nil {
-				out.AddError("Failed to get topics", err)
+				out.AddError(err.Error())
 				return
 			}
-			topicID := ""
-			for _, t := range topics {
-				if t.Slug == topic {
-					topicID = t.ID
-				}
-			}
+			client := newClient(token)
 
-			if topicID == "" {
-				out.AddError("Topic not found", errors.New("Topic not found"))
+			if _, err := client.GetTopic(context.Background(), app, topic); err != nil {
+				if errors.Is(err, api.ErrNotFound) {
+					out.AddCodedError(output.CodeNotFound, "Topic not found")
+				} else {
+					out.AddError("Failed to get topic", err)
+				}
 				return
 			}
 
-			subscriptions, err := client.GetSubscriptions(context.Background(), app, topic)
+			subscriptions, more, err := listItems(context.Background(), cmd, client.ListSubscriptions(app, topic, listOptions(cmd)))
 			if err != nil {
 				out.AddError("Failed to get subscriptions", err)
 				return
@@ -61,8 +61,15 @@ func init() {
 				out.AddMessage(subscriptionSlug)
 			}
 
+			if more {
+				out.AddMessage("\n" + moreMessage(len(subscriptions), "subscriptions"))
+			}
+
 			out.SetData(subscriptions)
-		})})
+		}),
+	}
+	addListFlags(listCmd, "subscriptions")
+	subCommand.AddCommand(listCmd)
 
 	createCmd := &cobra.Command{
 		Use:   "create [topic] [name]",
@@ -79,9 +86,13 @@ func init() {
 
 			if subType == "push" && endpoint == "" {
 				for {
-					survey.AskOne(&survey.Input{
+					err := ask(&survey.Input{
 						Message: "Specify the endpoint for the subscription",
-					}, &endpoint)
+					}, &endpoint, "--endpoint for push subscriptions")
+					if err != nil {
+						out.AddError("Missing endpoint", err)
+						return
+					}
 
 					if !util.IsValidEndpoint(endpoint) {
 						warnText := lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render("Endpoint is not valid, we only support HTTPS endpoints")
@@ -93,7 +104,7 @@ func init() {
 			}
 
 			topic := args[0]
-			client := api.NewSailhouseClient(token)
+			client := newClient(token)
 
 			topics, err := client.GetTopics(context.Background(), app)
 			if err != nil {
@@ -109,7 +120,7 @@ func init() {
 			}
 
 			if topicID == "" {
-				out.AddError("Topic not found")
+				out.AddCodedError(output.CodeNotFound, "Topic not found")
 				return
 			}
 
@@ -123,11 +134,9 @@ func init() {
 				FilterValue: filterValue,
 			})
 			if err != nil {
-				if respErr := new(requests.ResponseError); errors.As(err, &respErr) {
-					if respErr.StatusCode == 409 {
-						out.AddError("Subscription already exists")
-						return
-					}
+				if errors.Is(err, api.ErrConflict) {
+					out.AddCodedError(output.CodeConflict, "Subscription already exists")
+					return
 				}
 				out.AddError("Error creating subscription", err)
 				return
@@ -155,7 +164,7 @@ func init() {
 
 			topic := args[0]
 			subscription := args[1]
-			client := api.NewSailhouseClient(token)
+			client := newClient(token)
 
 			topics, err := client.GetTopics(context.Background(), app)
 			if err != nil {
@@ -171,13 +180,14 @@ func init() {
 			}
 
 			if topicID == "" {
-				out.AddError("Topic not found")
+				out.AddCodedError(output.CodeNotFound, "Topic not found")
 				return
 			}
 
 			sub, err := client.GetSubscription(context.Background(), app, topic, subscription)
 			if err != nil {
 				out.AddError("Error fetching subscription", err)
+				return
 			}
 
 			out.SetData(*sub)
@@ -187,8 +197,333 @@ func init() {
 			if sub.Endpoint != "" {
 				out.AddMessage(fmt.Sprintf("Endpoint: %s", sub.Endpoint))
 			}
+			if sub.FilterPath != "" {
+				out.AddMessage(fmt.Sprintf("Filter: %s = %s", sub.FilterPath, sub.FilterValue))
+			}
+			if sub.Paused {
+				out.AddMessage("Paused: yes")
+			}
 		}),
 	})
 
+	updateCmd := &cobra.Command{
+		Use:   "update [topic] [name]",
+		Short: "Change a subscription in place",
+		Long: `Change a subscription in place.
+
+Only the settings passed as flags are changed. Unlike deleting and recreating
+the subscription, events waiting to be delivered are kept. Pass empty filter
+flags to remove the filter.`,
+		Example: `  sailhouse subs update orders billing --endpoint https://billing.example.com/events
+  sailhouse subs update orders billing --filter-path "" --filter-value ""`,
+		Args: cobra.ExactArgs(2),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[models.Subscription]) {
+			token := viper.GetString("token")
+			app := getApp()
+
+			update := api.UpdateSubscription{}
+			if cmd.Flags().Changed("type") {
+				subType, _ := cmd.Flags().GetString("type")
+				if subType != "pull" && subType != "push" {
+					out.AddCodedError(output.CodeValidation, "Type must be pull or push")
+					return
+				}
+				update.Type = &subType
+			}
+			if cmd.Flags().Changed("endpoint") {
+				endpoint, _ := cmd.Flags().GetString("endpoint")
+				if !util.IsValidEndpoint(endpoint) {
+					out.AddCodedError(output.CodeValidation, "Endpoint is not valid, we only support HTTPS endpoints")
+					return
+				}
+				update.Endpoint = &endpoint
+			}
+			if cmd.Flags().Changed("filter-path") {
+				filterPath, _ := cmd.Flags().GetString("filter-path")
+				update.FilterPath = &filterPath
+			}
+			if cmd.Flags().Changed("filter-value") {
+				filterValue, _ := cmd.Flags().GetString("filter-value")
+				update.FilterValue = &filterValue
+			}
+
+			if update.Type == nil && update.Endpoint == nil && update.FilterPath == nil && update.FilterValue == nil {
+				out.AddCodedError(output.CodeValidation, "Nothing to update, pass --type, --endpoint, --filter-path or --filter-value")
+				return
+			}
+
+			client := newClient(token)
+
+			// Push subscriptions need somewhere to deliver to.
+			if update.Type != nil && *update.Type == "push" && update.Endpoint == nil {
+				current, err := client.GetSubscription(context.Background(), app, args[0], args[1])
+				if err != nil {
+					out.AddError("Error getting subscription", err)
+					return
+				}
+
+				if current.Endpoint == "" {
+					out.AddCodedError(output.CodeValidation, "Push subscriptions need an endpoint, pass --endpoint")
+					return
+				}
+			}
+
+			sub, err := client.UpdateSubscription(context.Background(), app, args[0], args[1], update)
+			if err != nil {
+				out.AddError("Error updating subscription", err)
+				return
+			}
+
+			out.SetData(sub)
+			out.AddMessage(fmt.Sprintf("Subscription %s updated", sub.Slug))
+		}),
+	}
+
+	updateCmd.Flags().StringP("type", "t", "", "Subscription type")
+	updateCmd.Flags().StringP("endpoint", "e", "", "Endpoint for push subscriptions")
+	updateCmd.Flags().StringP("filter-path", "p", "", "Filter path")
+	updateCmd.Flags().StringP("filter-value", "v", "", "Filter value")
+
+	subCommand.AddCommand(updateCmd)
+
+	subCommand.AddCommand(&cobra.Command{
+		Use:   "pause [topic] [name]",
+		Short: "Stop delivering events to a subscription",
+		Long: `Stop delivering events to a subscription.
+
+Events published while the subscription is paused are kept and delivered
+once it's resumed.`,
+		Args: cobra.ExactArgs(2),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[models.Subscription]) {
+			setSubscriptionPaused(args[0], args[1], true, out)
+		}),
+	})
+
+	subCommand.AddCommand(&cobra.Command{
+		Use:   "resume [topic] [name]",
+		Short: "Resume delivering events to a paused subscription",
+		Args:  cobra.ExactArgs(2),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[models.Subscription]) {
+			setSubscriptionPaused(args[0], args[1], false, out)
+		}),
+	})
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete [topic] [name]",
+		Short: "Delete a subscription",
+		Args:  cobra.ExactArgs(2),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[models.Subscription]) {
+			token := viper.GetString("token")
+			app := getApp()
+
+			yes, _ := cmd.Flags().GetBool("yes")
+			topic := args[0]
+			subscription := args[1]
+
+			client := newClient(token)
+
+			sub, err := client.GetSubscription(context.Background(), app, topic, subscription)
+			if err != nil {
+				out.AddError("Error fetching subscription", err)
+				return
+			}
+
+			if !yes {
+				confirmed := false
+				err := ask(&survey.Confirm{
+					Message: fmt.Sprintf("Delete subscription %s/%s and any events waiting to be delivered to it?", topic, subscription),
+				}, &confirmed, "--yes to confirm the deletion")
+				if err != nil {
+					out.AddError("Deletion not confirmed", err)
+					return
+				}
+
+				if !confirmed {
+					out.AddMessage("Deletion cancelled")
+					return
+				}
+			}
+
+			if err := client.DeleteSubscription(context.Background(), app, topic, subscription); err != nil {
+				out.AddError("Error deleting subscription", err)
+				return
+			}
+
+			out.SetData(*sub)
+			out.AddMessage(fmt.Sprintf("Subscription %s deleted", sub.Slug))
+		}),
+	}
+	deleteCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+
+	subCommand.AddCommand(deleteCmd)
+
+	pullCmd := &cobra.Command{
+		Use:   "pull [topic] [name]",
+		Short: "Pull events from a pull subscription",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			token := viper.GetString("token")
+			app := getApp()
+
+			limit, _ := cmd.Flags().GetInt("limit")
+			ack, _ := cmd.Flags().GetBool("ack")
+			ackAfter, _ := cmd.Flags().GetDuration("ack-after")
+			follow, _ := cmd.Flags().GetBool("follow")
+			wait, _ := cmd.Flags().GetDuration("wait")
+
+			if ackAfter > 0 {
+				ack = true
+			}
+
+			topic := args[0]
+			subscription := args[1]
+			client := newClient(token)
+			stream := output.NewStream[models.Event]()
+			defer stream.ExitOnError()
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			sub, err := client.GetSubscription(ctx, app, topic, subscription)
+			if err != nil {
+				stream.Error("Error fetching subscription", err)
+				return
+			}
+
+			if sub.Type != "pull" {
+				stream.Error(fmt.Sprintf("Subscription %s is a %s subscription, only pull subscriptions can be pulled from", sub.Slug, sub.Type))
+				return
+			}
+
+			if !follow {
+				wait = 0
+			}
+
+			for {
+				events, err := client.PullEvents(ctx, app, topic, subscription, api.PullEvents{
+					Limit: limit,
+					Wait:  wait,
+				})
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+
+					stream.Error("Error pulling events", err)
+					if !follow {
+						return
+					}
+
+					if !sleepContext(ctx, 5*time.Second) {
+						return
+					}
+					continue
+				}
+
+				if len(events) == 0 && !follow {
+					stream.Message("No events available")
+				}
+
+				for _, event := range events {
+					stream.Write(event, formatEvent(event))
+				}
+
+				if ack && len(events) > 0 {
+					// --ack-after is waited once per batch, then the whole
+					// batch is acknowledged.
+					if !sleepContext(ctx, ackAfter) {
+						stream.Message(fmt.Sprintf("Interrupted, %d events weren't acknowledged and will be redelivered", len(events)))
+						return
+					}
+
+					for i, event := range events {
+						if ctx.Err() != nil {
+							stream.Message(fmt.Sprintf("Interrupted, %d events weren't acknowledged and will be redelivered", len(events)-i))
+							return
+						}
+
+						if err := client.AckEvent(ctx, app, topic, subscription, event.ID); err != nil {
+							stream.Error(fmt.Sprintf("Error acknowledging event %s", event.ID), err)
+						}
+					}
+				}
+
+				if !follow {
+					return
+				}
+			}
+		},
+	}
+
+	pullCmd.Flags().IntP("limit", "l", 10, "Maximum number of events to pull per request")
+	pullCmd.Flags().Bool("ack", false, "Acknowledge each event after printing it")
+	pullCmd.Flags().Duration("ack-after", 0, "Wait this long after printing each batch before acknowledging it (implies --ack), unacknowledged events are redelivered if interrupted")
+	pullCmd.Flags().Bool("follow", false, "Keep long-polling for new events until interrupted")
+	pullCmd.Flags().Duration("wait", 20*time.Second, "How long each long-poll request waits for events when following")
+
+	subCommand.AddCommand(pullCmd)
+
 	rootCmd.AddCommand(subCommand)
 }
+
+func setSubscriptionPaused(topic, subscription string, paused bool, out *output.Output[models.Subscription]) {
+	token := viper.GetString("token")
+	app := getApp()
+	client := newClient(token)
+
+	sub, err := client.UpdateSubscription(context.Background(), app, topic, subscription, api.UpdateSubscription{Paused: &paused})
+	if err != nil {
+		if paused {
+			out.AddError("Error pausing subscription", err)
+		} else {
+			out.AddError("Error resuming subscription", err)
+		}
+		return
+	}
+
+	out.SetData(sub)
+	if paused {
+		out.AddMessage(fmt.Sprintf("Subscription %s paused, events will be held until it's resumed", sub.Slug))
+	} else {
+		out.AddMessage(fmt.Sprintf("Subscription %s resumed", sub.Slug))
+	}
+}
+
+func formatEvent(event models.Event) string {
+	id := lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render(event.ID)
+
+	line := fmt.Sprintf("%s %s", id, string(event.Data))
+	if len(event.Metadata) == 0 {
+		return line
+	}
+
+	keys := make([]string, 0, len(event.Metadata))
+	for k := range event.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, event.Metadata[k]))
+	}
+
+	return fmt.Sprintf("%s %s", line, strings.Join(pairs, ","))
+}
+
+// sleepContext waits for d, returning false if ctx is cancelled first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/AlecAivazis/survey/v2"
-	"github.com/sailhouse/sailhouse/api"
 	"github.com/sailhouse/sailhouse/config"
 	"github.com/sailhouse/sailhouse/models"
 	"github.com/sailhouse/sailhouse/util/output"
@@ -19,17 +18,17 @@ func init() {
 		Short: "Manage teams",
 	}
 
-	teamsCmd.AddCommand(&cobra.Command{
+	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List teams",
 		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[[]models.Team]) {
 			token := viper.GetString("token")
-			client := api.NewSailhouseClient(token)
+			client := newClient(token)
 
-			teams, err := client.GetTeams(context.Background())
+			teams, more, err := listItems(context.Background(), cmd, client.ListTeams(listOptions(cmd)))
 
 			if err != nil {
-				out.AddError(fmt.Sprintf("Error getting teams: %s", err))
+				out.AddError("Error getting teams", err)
 				return
 			}
 
@@ -38,7 +37,14 @@ func init() {
 			for _, team := range teams {
 				out.AddMessage(team.Slug)
 			}
-		})})
+
+			if more {
+				out.AddMessage("\n" + moreMessage(len(teams), "teams"))
+			}
+		}),
+	}
+	addListFlags(listCmd, "teams")
+	teamsCmd.AddCommand(listCmd)
 
 	teamsCmd.AddCommand(&cobra.Command{
 		Use:   "set [team-slug]",
@@ -46,16 +52,16 @@ func init() {
 		Args:  cobra.MaximumNArgs(1),
 		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[models.Team]) {
 			token := viper.GetString("token")
-			client := api.NewSailhouseClient(token)
+			client := newClient(token)
 
 			teams, err := client.GetTeams(context.Background())
 			if err != nil {
-				out.AddError(fmt.Sprintf("Error getting teams: %s", err))
+				out.AddError("Error getting teams", err)
 				return
 			}
 
 			var teamSlug string
-			if len(args) > 1 && args[0] != "" {
+			if len(args) == 1 && args[0] != "" {
 				teamSlug = args[0]
 			} else {
 				if len(teams) == 1 {
@@ -66,11 +72,15 @@ func init() {
 						options = append(options, team.Slug)
 					}
 
-					survey.AskOne(
+					err := ask(
 						&survey.Select{
 							Message: "Team slug:",
 							Options: options,
-						}, &teamSlug)
+						}, &teamSlug, "the team slug as an argument: sailhouse teams set [team-slug]")
+					if err != nil {
+						out.AddError("Missing team slug", err)
+						return
+					}
 				}
 			}
 
@@ -83,17 +93,24 @@ func init() {
 			}
 
 			if team == nil {
-				out.AddError(fmt.Sprintf("Team %s not found", teamSlug))
+				out.AddCodedError(output.CodeNotFound, fmt.Sprintf("Team %s not found", teamSlug))
 				return
 			}
 
 			out.SetData(*team)
 			out.AddMessage(fmt.Sprintf("Team %s set", team.Slug))
 
-			profile := config.LoadProfile()
+			profile, err := config.LoadProfile(viper.GetString("profile"))
+			if err != nil {
+				out.AddError("Failed to load profile", err)
+				return
+			}
 
 			profile.Team = team.Slug
-			profile.SaveProfile()
+			if err := profile.SaveProfile(); err != nil {
+				out.AddError("Failed to save profile", err)
+				return
+			}
 		})})
 
 	rootCmd.AddCommand(teamsCmd)
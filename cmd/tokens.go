@@ -2,8 +2,14 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/AlecAivazis/survey/v2"
 	"github.com/sailhouse/sailhouse/api"
 	"github.com/sailhouse/sailhouse/models"
 	"github.com/sailhouse/sailhouse/util/output"
@@ -11,34 +17,162 @@ import (
 	"github.com/spf13/viper"
 )
 
+type RotatedToken struct {
+	Token string `json:"token"`
+	ID    string `json:"id"`
+	// Revoked is the old token, which stops working at RevokesAt.
+	Revoked   models.TokenPreview `json:"revoked"`
+	RevokesAt *time.Time          `json:"revokes_at,omitempty"`
+	// RevokeError is set when the new token was created but the old one
+	// couldn't be revoked.
+	RevokeError string `json:"revoke_error,omitempty"`
+}
+
 func init() {
 	tokenCmd := &cobra.Command{
 		Use:   "tokens",
 		Short: "Manage tokens",
 	}
 
-	tokenCmd.AddCommand(&cobra.Command{
+	createCmd := &cobra.Command{
 		Use:   "create",
 		Short: "Create a token",
-		Args:  cobra.NoArgs,
-		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[string]) {
+		Long: `Create a token.
+
+Tokens have full access to the app unless limited with --scope: publish
+tokens can only publish events, read tokens can only read. The token is only
+shown once.
+
+To keep the token out of terminal scrollback, send it straight to where it's
+needed instead: --write-env sets it in a dotenv file, --k8s-secret wraps it in
+a Kubernetes Secret manifest and --stdin-to pipes it, or the manifest, into a
+command. The token isn't printed when any of these are used. If the token
+can't be delivered it's revoked again.`,
+		Example: `  sailhouse tokens create --name ci --scope publish --expires-in 90d
+  sailhouse tokens create --write-env .env
+  sailhouse tokens create --k8s-secret sailhouse --stdin-to "kubectl apply -f -"
+  sailhouse tokens create --stdin-to "gh secret set SAILHOUSE_TOKEN"`,
+		Args: cobra.NoArgs,
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[any]) {
 			token := viper.GetString("token")
 			app := getApp()
+			writeEnv, _ := cmd.Flags().GetString("write-env")
+			envVar, _ := cmd.Flags().GetString("env-var")
+			k8sName, _ := cmd.Flags().GetString("k8s-secret")
+			k8sNamespace, _ := cmd.Flags().GetString("k8s-namespace")
+			stdinTo, _ := cmd.Flags().GetString("stdin-to")
 
-			client := api.NewSailhouseClient(token)
+			newToken, err := tokenOptions(cmd)
+			if err == nil {
+				err = validateEnvVar(envVar)
+			}
+			if err == nil && k8sName != "" {
+				err = validateK8sName("--k8s-secret", k8sName)
+			}
+			if err == nil && k8sNamespace != "" {
+				if k8sName == "" {
+					err = errors.New("--k8s-namespace needs --k8s-secret")
+				} else {
+					err = validateK8sName("--k8s-namespace", k8sNamespace)
+				}
+			}
+			if err != nil {
+				out.AddCodedError(output.CodeValidation, err.Error())
+				return
+			}
 
-			createdToken, err := client.CreateToken(context.Background(), app)
+			client := newClient(token)
+
+			createdToken, err := client.CreateToken(context.Background(), app, newToken)
 
 			if err != nil {
-				out.AddError(fmt.Sprintf("Error creating token: %s", err))
+				out.AddError("Error creating token", err)
+				return
+			}
+
+			if writeEnv == "" && k8sName == "" && stdinTo == "" {
+				out.SetData(createdToken.Token)
+				out.AddMessage(createdToken.Token)
 				return
 			}
 
-			out.SetData(createdToken)
-			out.AddMessage(createdToken)
-		})})
+			// Nobody has seen the token, so one that couldn't be delivered
+			// is revoked rather than left working.
+			failed := func(message string, err error) {
+				out.AddError(message+", the new token has been revoked", err)
+				if err := client.DeleteToken(context.Background(), app, createdToken.ID); err != nil {
+					out.AddError(fmt.Sprintf("Failed to revoke the new token, revoke it with `sailhouse tokens revoke %s`", createdToken.ID), err)
+				}
+			}
+
+			messages := []string{fmt.Sprintf("Created token %s", describeToken(createdToken.TokenPreview))}
+
+			if writeEnv != "" {
+				if err := upsertEnvFile(writeEnv, envVar, createdToken.Token); err != nil {
+					failed(fmt.Sprintf("Failed to write the token to %s", writeEnv), err)
+					return
+				}
+				messages = append(messages, fmt.Sprintf("Set %s in %s", envVar, writeEnv))
+			}
+
+			var secret *k8sSecret
+			var manifest []byte
+			if k8sName != "" {
+				s := newK8sSecret(k8sName, k8sNamespace, envVar, createdToken.Token)
+				secret = &s
+				manifest, err = secret.manifest(viper.Get("format") == "json")
+				if err != nil {
+					failed("Failed to encode the Kubernetes secret", err)
+					return
+				}
+			}
+
+			if stdinTo != "" {
+				input := []byte(createdToken.Token)
+				if secret != nil {
+					input = manifest
+				}
+
+				if err := pipeToCommand(stdinTo, input); err != nil {
+					failed("Failed to pipe the token", err)
+					return
+				}
+
+				if secret != nil {
+					messages = append(messages, fmt.Sprintf("Piped Kubernetes secret %s to `%s`", k8sName, stdinTo))
+				} else {
+					messages = append(messages, fmt.Sprintf("Piped the token to `%s`", stdinTo))
+				}
+			}
+
+			// Without --stdin-to the manifest is the output, ready to pipe to
+			// kubectl, so everything else goes to stderr.
+			if secret != nil && stdinTo == "" {
+				for _, message := range messages {
+					fmt.Fprintln(os.Stderr, message)
+				}
+				out.SetData(secret)
+				out.AddMessage(strings.TrimSuffix(string(manifest), "\n"))
+				return
+			}
+
+			out.SetData(createdToken.TokenPreview)
+			for _, message := range messages {
+				out.AddMessage(message)
+			}
+		}),
+	}
+	addTokenFlags(createCmd)
+	createCmd.Flags().String("name", "", "Name to identify the token by")
+	createCmd.Flags().String("write-env", "", "Set the token in this dotenv file instead of printing it, e.g. .env")
+	createCmd.Flags().String("env-var", "SAILHOUSE_TOKEN", "Variable name used with --write-env and key used with --k8s-secret")
+	createCmd.Flags().String("k8s-secret", "", "Output a Kubernetes Secret manifest with this name instead of the token")
+	createCmd.Flags().String("k8s-namespace", "", "Namespace for the --k8s-secret manifest")
+	createCmd.Flags().String("stdin-to", "", "Pipe the token, or the --k8s-secret manifest, to this shell command instead of printing it")
+
+	tokenCmd.AddCommand(createCmd)
 
-	tokenCmd.AddCommand(&cobra.Command{
+	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List tokens",
 		Args:  cobra.NoArgs,
@@ -46,11 +180,11 @@ func init() {
 			token := viper.GetString("token")
 			app := getApp()
 
-			client := api.NewSailhouseClient(token)
+			client := newClient(token)
 
-			tokens, err := client.GetTokens(context.Background(), app)
+			tokens, more, err := listItems(context.Background(), cmd, client.ListTokens(app, listOptions(cmd)))
 			if err != nil {
-				out.AddError(fmt.Sprintf("Error listing tokens: %s", err))
+				out.AddError("Error listing tokens", err)
 				return
 			}
 
@@ -58,10 +192,28 @@ func init() {
 
 			table := output.NewTable()
 
-			table.AddColumns("ID", "Preview")
+			table.AddColumns("ID", "Name", "Preview", "Scope", "Created", "Last Used", "Expires")
 
+			now := time.Now()
 			for _, token := range tokens {
-				table.AddRow(token.ID, token.Preview)
+				expires := formatTokenTime(token.ExpiresAt, "never")
+				if token.ExpiresAt != nil && !token.ExpiresAt.After(now) {
+					expires += " (expired)"
+				}
+
+				table.AddRow(
+					token.ID,
+					token.Name,
+					token.Preview,
+					token.Scope,
+					formatTokenTime(&token.CreatedAt, ""),
+					formatTokenTime(token.LastUsedAt, "never"),
+					expires,
+				)
+			}
+
+			if more {
+				table.SetFooter(moreMessage(len(tokens), "tokens"))
 			}
 
 			if len(tokens) == 0 {
@@ -71,7 +223,244 @@ func init() {
 			}
 
 		}),
-	})
+	}
+	addListFlags(listCmd, "tokens")
+	tokenCmd.AddCommand(listCmd)
+
+	revokeCmd := &cobra.Command{
+		Use:   "revoke [id]",
+		Short: "Revoke a token immediately",
+		Long: `Revoke a token immediately.
+
+Anything still using the token starts failing straight away. To replace a
+token without downtime use tokens rotate instead.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[models.TokenPreview]) {
+			token := viper.GetString("token")
+			app := getApp()
+			yes, _ := cmd.Flags().GetBool("yes")
+
+			client := newClient(token)
+
+			revoked, err := selectToken(context.Background(), client, app, args, "the token ID as an argument: sailhouse tokens revoke [id]")
+			if err != nil {
+				out.AddError("Failed to find token", err)
+				return
+			}
+
+			if !yes {
+				confirmed := false
+				err := ask(&survey.Confirm{
+					Message: fmt.Sprintf("Revoke token %s? Anything using it will stop working", describeToken(revoked)),
+				}, &confirmed, "--yes to confirm revoking the token")
+				if err != nil {
+					out.AddError("Revocation not confirmed", err)
+					return
+				}
+
+				if !confirmed {
+					out.AddMessage("Revocation cancelled")
+					return
+				}
+			}
+
+			if err := client.DeleteToken(context.Background(), app, revoked.ID); err != nil {
+				out.AddError("Error revoking token", err)
+				return
+			}
+
+			out.SetData(revoked)
+			out.AddMessage(fmt.Sprintf("Revoked token %s", describeToken(revoked)))
+		}),
+	}
+	revokeCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+
+	tokenCmd.AddCommand(revokeCmd)
+
+	rotateCmd := &cobra.Command{
+		Use:   "rotate [id]",
+		Short: "Replace a token with a new one",
+		Long: `Replace a token with a new one.
+
+Creates a token with the same name and scope, prints it, and sets the old
+token to expire after the grace period so there's time to roll the new one
+out. Pass --grace 0 to revoke the old token straight away.`,
+		Example: `  sailhouse tokens rotate 4jqz1tecemwj --grace 24h`,
+		Args:    cobra.MaximumNArgs(1),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[RotatedToken]) {
+			token := viper.GetString("token")
+			app := getApp()
+			grace, _ := cmd.Flags().GetDuration("grace")
+
+			if grace < 0 {
+				out.AddCodedError(output.CodeValidation, "--grace can't be negative")
+				return
+			}
+
+			newToken, err := tokenOptions(cmd)
+			if err != nil {
+				out.AddCodedError(output.CodeValidation, err.Error())
+				return
+			}
+
+			client := newClient(token)
+
+			old, err := selectToken(context.Background(), client, app, args, "the token ID as an argument: sailhouse tokens rotate [id]")
+			if err != nil {
+				out.AddError("Failed to find token", err)
+				return
+			}
+
+			newToken.Name = old.Name
+			if !cmd.Flags().Changed("scope") {
+				newToken.Scope = old.Scope
+			}
+
+			created, err := client.CreateToken(context.Background(), app, newToken)
+			if err != nil {
+				out.AddError("Error creating token", err)
+				return
+			}
+
+			rotated := RotatedToken{Token: created.Token, ID: created.ID, Revoked: old}
+
+			// Never extend a token that was going to expire sooner.
+			revokesAt := time.Now().Add(grace).UTC()
+			if old.ExpiresAt != nil && old.ExpiresAt.Before(revokesAt) {
+				revokesAt = *old.ExpiresAt
+			}
+
+			if grace == 0 {
+				err = client.DeleteToken(context.Background(), app, old.ID)
+			} else {
+				_, err = client.UpdateToken(context.Background(), app, old.ID, api.UpdateToken{ExpiresAt: &revokesAt})
+			}
+
+			out.AddMessage(created.Token)
+
+			// The new token can't be fetched again, so failing to revoke the
+			// old one is reported alongside it rather than as an error that
+			// would hide it.
+			switch {
+			case err != nil:
+				rotated.RevokeError = output.NewError("", err).Message
+				out.AddMessage(fmt.Sprintf("\nFailed to revoke token %s, revoke it with `sailhouse tokens revoke %s`: %s", describeToken(old), old.ID, rotated.RevokeError))
+			case grace == 0:
+				rotated.RevokesAt = &revokesAt
+				out.AddMessage(fmt.Sprintf("\nRevoked token %s", describeToken(old)))
+			default:
+				rotated.RevokesAt = &revokesAt
+				out.AddMessage(fmt.Sprintf("\nToken %s stops working at %s", describeToken(old), revokesAt.Local().Format(time.DateTime)))
+			}
+
+			out.SetData(rotated)
+		}),
+	}
+	addTokenFlags(rotateCmd)
+	rotateCmd.Flags().Duration("grace", time.Hour, "How long the old token keeps working")
+
+	tokenCmd.AddCommand(rotateCmd)
 
 	rootCmd.AddCommand(tokenCmd)
 }
+
+func addTokenFlags(cmd *cobra.Command) {
+	cmd.Flags().String("scope", api.TokenScopeFull, "What the token can do [full | publish | read]")
+	cmd.Flags().String("expires-in", "", "Expire the token after this long, e.g. 90d or 12h (never expires by default)")
+}
+
+// tokenOptions reads --scope and --expires-in.
+func tokenOptions(cmd *cobra.Command) (api.CreateToken, error) {
+	scope, _ := cmd.Flags().GetString("scope")
+	switch scope {
+	case api.TokenScopeFull, api.TokenScopePublish, api.TokenScopeRead:
+	default:
+		return api.CreateToken{}, fmt.Errorf("--scope must be %s, %s or %s", api.TokenScopeFull, api.TokenScopePublish, api.TokenScopeRead)
+	}
+
+	name, _ := cmd.Flags().GetString("name")
+	newToken := api.CreateToken{Name: name, Scope: scope}
+
+	if expiresIn, _ := cmd.Flags().GetString("expires-in"); expiresIn != "" {
+		d, err := parseExpiry(expiresIn)
+		if err != nil {
+			return api.CreateToken{}, err
+		}
+
+		expiresAt := time.Now().Add(d).UTC()
+		newToken.ExpiresAt = &expiresAt
+	}
+
+	return newToken, nil
+}
+
+// parseExpiry parses a duration, also accepting whole days like "30d".
+func parseExpiry(s string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid --expires-in %q, use a positive duration like 90d or 12h", s)
+	}
+
+	return d, nil
+}
+
+// selectToken finds the token with the ID in args, or asks which token to
+// use when there isn't one.
+func selectToken(ctx context.Context, client *api.SailhouseClient, app string, args []string, missing string) (models.TokenPreview, error) {
+	tokens, err := client.GetTokens(ctx, app)
+	if err != nil {
+		return models.TokenPreview{}, err
+	}
+
+	if len(args) == 1 {
+		for _, token := range tokens {
+			if token.ID == args[0] {
+				return token, nil
+			}
+		}
+
+		return models.TokenPreview{}, output.WithCode(output.CodeNotFound, fmt.Errorf("no token with ID %s", args[0]))
+	}
+
+	if len(tokens) == 0 {
+		return models.TokenPreview{}, output.WithCode(output.CodeNotFound, errors.New("the app has no tokens"))
+	}
+
+	options := []string{}
+	for _, token := range tokens {
+		options = append(options, describeToken(token))
+	}
+
+	var selected int
+	if err := ask(&survey.Select{Message: "Select a token:", Options: options}, &selected, missing); err != nil {
+		return models.TokenPreview{}, err
+	}
+
+	return tokens[selected], nil
+}
+
+func describeToken(token models.TokenPreview) string {
+	if token.Name != "" {
+		return fmt.Sprintf("%s (%s, %s)", token.ID, token.Name, token.Preview)
+	}
+
+	return fmt.Sprintf("%s (%s)", token.ID, token.Preview)
+}
+
+func formatTokenTime(t *time.Time, unset string) string {
+	if t == nil || t.IsZero() {
+		return unset
+	}
+
+	return t.Local().Format(time.DateTime)
+}
@@ -3,12 +3,13 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"regexp"
+	"os"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/sailhouse/sailhouse/api"
 	"github.com/sailhouse/sailhouse/models"
+	"github.com/sailhouse/sailhouse/util"
 	"github.com/sailhouse/sailhouse/util/output"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -20,18 +21,18 @@ func init() {
 		Short: "Manage topics",
 	}
 
-	topicsCmd.AddCommand(&cobra.Command{
+	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List topics",
 		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[[]models.Topic]) {
 			token := viper.GetString("token")
 			app := getApp()
-			client := api.NewSailhouseClient(token)
+			client := newClient(token)
 
-			topics, err := client.GetTopics(context.Background(), app)
+			topics, more, err := listItems(context.Background(), cmd, client.ListTopics(app, listOptions(cmd)))
 
 			if err != nil {
-				out.AddError(fmt.Sprintf("Error getting topics: %s", err))
+				out.AddError("Error getting topics", err)
 				return
 			}
 
@@ -44,9 +45,16 @@ func init() {
 				table.AddRow(topic.ID, slug)
 			}
 
+			if more {
+				table.SetFooter(moreMessage(len(topics), "topics"))
+			}
+
 			out.SetData(topics)
 			out.SetTable(table)
-		})})
+		}),
+	}
+	addListFlags(listCmd, "topics")
+	topicsCmd.AddCommand(listCmd)
 
 	topicsCmd.AddCommand(&cobra.Command{
 		Use:   "create [topic]",
@@ -55,30 +63,210 @@ func init() {
 		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[string]) {
 			token := viper.GetString("token")
 			app := getApp()
-			client := api.NewSailhouseClient(token)
+			client := newClient(token)
 
 			var topicSlug string
 			if len(args) == 1 {
 				topicSlug = args[0]
 			} else {
-				survey.AskOne(&survey.Input{Message: "Topic slug"}, &topicSlug)
+				err := ask(&survey.Input{Message: "Topic slug"}, &topicSlug, "the topic slug as an argument: sailhouse topics create [topic]")
+				if err != nil {
+					out.AddError("Missing topic slug", err)
+					return
+				}
 			}
 
-			slugRegex := regexp.MustCompile(`^[a-z0-9-]+$`)
-			if !slugRegex.MatchString(topicSlug) {
-				out.AddError("Topic slug must be lowercase and only contain characters a-z or '-'")
+			if !util.IsValidSlug(topicSlug) {
+				out.AddCodedError(output.CodeValidation, "Topic slug must be lowercase and only contain characters a-z or '-'")
 				return
 			}
 
-			err := client.CreateTopic(context.Background(), app, topicSlug)
+			_, err := client.CreateTopic(context.Background(), app, api.CreateTopic{Slug: topicSlug})
 
 			if err != nil {
 				out.AddError("Failed to create topic", err)
+				return
 			}
 
 			out.SetData(topicSlug)
 			out.AddMessage(fmt.Sprintf("Created topic %s", topicSlug))
 		})})
 
+	topicsCmd.AddCommand(&cobra.Command{
+		Use:   "view [topic]",
+		Short: "Show a topic and its subscriptions",
+		Args:  cobra.MaximumNArgs(1),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[TopicDetails]) {
+			token := viper.GetString("token")
+			app := getApp()
+
+			topicSlug, err := getTopic(args)
+			if err != nil {
+				out.AddError(err.Error())
+				return
+			}
+
+			client := newClient(token)
+
+			details, err := getTopicDetails(context.Background(), client, app, topicSlug)
+			if err != nil {
+				out.AddError("Failed to get topic", err)
+				return
+			}
+
+			schemaKey := details.SchemaKey
+			if schemaKey == "" {
+				schemaKey = "none"
+			}
+
+			out.SetData(details)
+			out.AddMessage(fmt.Sprintf("ID: %s", details.ID))
+			out.AddMessage(fmt.Sprintf("Slug: %s", details.Slug))
+			out.AddMessage(fmt.Sprintf("Schema key: %s", schemaKey))
+			out.AddMessage(fmt.Sprintf("Subscriptions: %d", details.SubscriptionCount))
+			for _, slug := range details.Subscriptions {
+				out.AddMessage(fmt.Sprintf(" - %s", slug))
+			}
+		}),
+	})
+
+	updateCmd := &cobra.Command{
+		Use:   "update [topic]",
+		Short: "Change a topic's settings",
+		Long: `Change a topic's settings.
+
+Only the settings passed as flags are changed. Pass an empty --schema-key to
+stop a schema file managing the topic, so apply --prune no longer deletes it.`,
+		Example: `  sailhouse topics update orders --slug order-events
+  sailhouse topics update orders --schema-key ""`,
+		Args: cobra.MaximumNArgs(1),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[models.Topic]) {
+			token := viper.GetString("token")
+			app := getApp()
+
+			topicSlug, err := getTopic(args)
+			if err != nil {
+				out.AddError(err.Error())
+				return
+			}
+
+			update := api.UpdateTopic{}
+			if cmd.Flags().Changed("slug") {
+				slug, _ := cmd.Flags().GetString("slug")
+				if !util.IsValidSlug(slug) {
+					out.AddCodedError(output.CodeValidation, "Topic slug must be lowercase and only contain characters a-z or '-'")
+					return
+				}
+				update.Slug = &slug
+			}
+			if cmd.Flags().Changed("schema-key") {
+				schemaKey, _ := cmd.Flags().GetString("schema-key")
+				update.SchemaKey = &schemaKey
+			}
+
+			if update.Slug == nil && update.SchemaKey == nil {
+				out.AddCodedError(output.CodeValidation, "Nothing to update, pass --slug or --schema-key")
+				return
+			}
+
+			client := newClient(token)
+
+			topic, err := client.UpdateTopic(context.Background(), app, topicSlug, update)
+			if err != nil {
+				out.AddError("Failed to update topic", err)
+				return
+			}
+
+			out.SetData(topic)
+			out.AddMessage(fmt.Sprintf("Updated topic %s", topic.Slug))
+		}),
+	}
+	updateCmd.Flags().String("slug", "", "Rename the topic")
+	updateCmd.Flags().String("schema-key", "", "Key of the schema file managing the topic")
+
+	topicsCmd.AddCommand(updateCmd)
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete [topic]",
+		Short: "Delete a topic and its subscriptions",
+		Args:  cobra.ExactArgs(1),
+		Run: output.WithOutput(func(cmd *cobra.Command, args []string, out *output.Output[TopicDetails]) {
+			token := viper.GetString("token")
+			app := getApp()
+
+			yes, _ := cmd.Flags().GetBool("yes")
+			topicSlug := args[0]
+
+			client := newClient(token)
+
+			details, err := getTopicDetails(context.Background(), client, app, topicSlug)
+			if err != nil {
+				out.AddError("Failed to get topic", err)
+				return
+			}
+
+			if !yes {
+				if details.SubscriptionCount > 0 {
+					warning := fmt.Sprintf("Deleting %s will also delete %d subscriptions and their undelivered events:", topicSlug, details.SubscriptionCount)
+					fmt.Fprintln(os.Stderr, lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(warning))
+					for _, slug := range details.Subscriptions {
+						fmt.Fprintf(os.Stderr, " - %s\n", slug)
+					}
+					fmt.Fprintln(os.Stderr)
+				}
+
+				confirmed := false
+				err := ask(&survey.Confirm{
+					Message: fmt.Sprintf("Delete topic %s?", topicSlug),
+				}, &confirmed, "--yes to confirm the deletion")
+				if err != nil {
+					out.AddError("Deletion not confirmed", err)
+					return
+				}
+
+				if !confirmed {
+					out.AddMessage("Deletion cancelled")
+					return
+				}
+			}
+
+			if err := client.DeleteTopic(context.Background(), app, topicSlug); err != nil {
+				out.AddError("Failed to delete topic", err)
+				return
+			}
+
+			out.SetData(details)
+			out.AddMessage(fmt.Sprintf("Deleted topic %s and %d subscriptions", topicSlug, details.SubscriptionCount))
+		}),
+	}
+	deleteCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+
+	topicsCmd.AddCommand(deleteCmd)
+
 	rootCmd.AddCommand(topicsCmd)
 }
+
+type TopicDetails struct {
+	models.Topic
+	SubscriptionCount int      `json:"subscription_count"`
+	Subscriptions     []string `json:"subscriptions"`
+}
+
+func getTopicDetails(ctx context.Context, client *api.SailhouseClient, app, topicSlug string) (TopicDetails, error) {
+	topic, err := client.GetTopic(ctx, app, topicSlug)
+	if err != nil {
+		return TopicDetails{}, err
+	}
+
+	subscriptions, err := client.GetSubscriptions(ctx, app, topicSlug)
+	if err != nil {
+		return TopicDetails{}, err
+	}
+
+	details := TopicDetails{Topic: *topic, SubscriptionCount: len(subscriptions), Subscriptions: []string{}}
+	for _, subscription := range subscriptions {
+		details.Subscriptions = append(details.Subscriptions, subscription.Slug)
+	}
+
+	return details, nil
+}
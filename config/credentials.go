@@ -0,0 +1,343 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Credential store kinds, as recorded in a profile's `credentials` field.
+const (
+	// CredentialsPlaintext keeps the token in profile.toml. It's the
+	// behaviour of profiles written before credential stores existed.
+	CredentialsPlaintext = "plaintext"
+	// CredentialsKeyring uses the OS keychain, Secret Service or Windows
+	// Credential Manager.
+	CredentialsKeyring = "keyring"
+	// CredentialsFile uses a passphrase-encrypted file next to
+	// profile.toml, for machines without a keyring.
+	CredentialsFile = "file"
+)
+
+const keyringService = "sailhouse-cli"
+
+var ErrCredentialNotFound = errors.New("no token stored for this profile")
+
+type CredentialStore interface {
+	Get(profile string) (string, error)
+	Set(profile, token string) error
+	Delete(profile string) error
+}
+
+// PassphraseFunc supplies the passphrase for the encrypted file store. It
+// reads SAILHOUSE_PASSPHRASE by default; the CLI replaces it with an
+// interactive prompt.
+var PassphraseFunc = func() (string, error) {
+	passphrase := os.Getenv("SAILHOUSE_PASSPHRASE")
+	if passphrase == "" {
+		return "", errors.New("SAILHOUSE_PASSPHRASE is not set")
+	}
+
+	return passphrase, nil
+}
+
+// fileStore is shared so the passphrase is only asked for once per run.
+var fileStore *EncryptedFileStore
+
+func NewCredentialStore(kind string) (CredentialStore, error) {
+	switch kind {
+	case CredentialsKeyring:
+		return KeyringStore{}, nil
+	case CredentialsFile:
+		if fileStore == nil {
+			fileStore = &EncryptedFileStore{
+				Path: filepath.Join(filepath.Dir(ProfilePath()), "credentials.enc"),
+				Passphrase: func() (string, error) {
+					return PassphraseFunc()
+				},
+			}
+		}
+		return fileStore, nil
+	default:
+		return nil, fmt.Errorf("unknown credential store %q, expected %s or %s", kind, CredentialsKeyring, CredentialsFile)
+	}
+}
+
+// KeyringAvailable reports whether the OS keyring can be used, which isn't
+// the case on headless Linux boxes without a Secret Service.
+func KeyringAvailable() bool {
+	_, err := keyring.Get(keyringService, "probe")
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}
+
+// DefaultCredentialStore is the store new tokens are saved to.
+func DefaultCredentialStore() string {
+	if KeyringAvailable() {
+		return CredentialsKeyring
+	}
+
+	return CredentialsFile
+}
+
+type KeyringStore struct{}
+
+func (KeyringStore) Get(profile string) (string, error) {
+	token, err := keyring.Get(keyringService, profile)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrCredentialNotFound
+	}
+
+	return token, err
+}
+
+func (KeyringStore) Set(profile, token string) error {
+	return keyring.Set(keyringService, profile, token)
+}
+
+func (KeyringStore) Delete(profile string) error {
+	err := keyring.Delete(keyringService, profile)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+// EncryptedFileStore keeps every profile's token in one file, encrypted with
+// AES-256-GCM under a key derived from a passphrase with scrypt.
+type EncryptedFileStore struct {
+	Path       string
+	Passphrase func() (string, error)
+
+	passphrase string
+}
+
+type encryptedFile struct {
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func (s *EncryptedFileStore) Get(profile string) (string, error) {
+	tokens, err := s.read()
+	if err != nil {
+		return "", err
+	}
+
+	token, ok := tokens[profile]
+	if !ok {
+		return "", ErrCredentialNotFound
+	}
+
+	return token, nil
+}
+
+func (s *EncryptedFileStore) Set(profile, token string) error {
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	tokens[profile] = token
+	return s.write(tokens)
+}
+
+func (s *EncryptedFileStore) Delete(profile string) error {
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := tokens[profile]; !ok {
+		return nil
+	}
+
+	delete(tokens, profile)
+	return s.write(tokens)
+}
+
+func (s *EncryptedFileStore) getPassphrase() (string, error) {
+	if s.passphrase != "" {
+		return s.passphrase, nil
+	}
+
+	passphrase, err := s.Passphrase()
+	if err != nil {
+		return "", err
+	}
+
+	if passphrase == "" {
+		return "", errors.New("passphrase cannot be empty")
+	}
+
+	s.passphrase = passphrase
+	return passphrase, nil
+}
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+}
+
+func (s *EncryptedFileStore) read() (map[string]string, error) {
+	tokens := map[string]string{}
+
+	fileBytes, err := os.ReadFile(s.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return tokens, nil
+		}
+		return nil, err
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(fileBytes, &file); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", s.Path, err)
+	}
+
+	passphrase, err := s.getPassphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := deriveKey(passphrase, file.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		// Don't cache a passphrase that doesn't work.
+		s.passphrase = ""
+		return nil, errors.New("could not decrypt credentials, is the passphrase correct?")
+	}
+
+	if err := json.Unmarshal(plaintext, &tokens); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (s *EncryptedFileStore) write(tokens map[string]string) error {
+	plaintext, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := s.getPassphrase()
+	if err != nil {
+		return err
+	}
+
+	file := encryptedFile{
+		Salt: make([]byte, 16),
+	}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return err
+	}
+
+	key, err := deriveKey(passphrase, file.Salt)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return err
+	}
+
+	file.Ciphertext = gcm.Seal(nil, file.Nonce, plaintext, nil)
+
+	fileBytes, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.Path, fileBytes, 0600)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// LoadToken returns the profile's token from wherever it's stored.
+func (p *Profile) LoadToken() (string, error) {
+	if p.Credentials == "" || p.Credentials == CredentialsPlaintext {
+		return p.Token, nil
+	}
+
+	store, err := NewCredentialStore(p.Credentials)
+	if err != nil {
+		return "", err
+	}
+
+	return store.Get(p.Name)
+}
+
+// StoreToken saves the token in the given credential store, or in the
+// profile itself for plaintext, and records where it went. The profile still
+// needs saving afterwards.
+func (p *Profile) StoreToken(kind, token string) error {
+	if kind == "" || kind == CredentialsPlaintext {
+		p.Token = token
+		p.Credentials = ""
+		return nil
+	}
+
+	store, err := NewCredentialStore(kind)
+	if err != nil {
+		return err
+	}
+
+	if err := store.Set(p.Name, token); err != nil {
+		return err
+	}
+
+	p.Token = ""
+	p.Credentials = kind
+	return nil
+}
+
+// DeleteToken removes the profile's token from its credential store.
+func (p *Profile) DeleteToken() error {
+	if p.Credentials != "" && p.Credentials != CredentialsPlaintext {
+		store, err := NewCredentialStore(p.Credentials)
+		if err != nil {
+			return err
+		}
+
+		if err := store.Delete(p.Name); err != nil {
+			return err
+		}
+	}
+
+	p.Token = ""
+	return nil
+}
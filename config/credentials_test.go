@@ -0,0 +1,86 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestStore(path, passphrase string) *EncryptedFileStore {
+	return &EncryptedFileStore{
+		Path:       path,
+		Passphrase: func() (string, error) { return passphrase, nil },
+	}
+}
+
+func TestEncryptedFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	store := newTestStore(path, "correct horse")
+
+	if _, err := store.Get("default"); !errors.Is(err, ErrCredentialNotFound) {
+		t.Fatalf("Get before Set = %v, want ErrCredentialNotFound", err)
+	}
+
+	if err := store.Set("default", "sh_default"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("staging", "sh_staging"); err != nil {
+		t.Fatal(err)
+	}
+
+	fileBytes, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(fileBytes, []byte("sh_default")) {
+		t.Error("the token is stored in plain text")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("file mode = %o, want 600", perm)
+	}
+
+	// A fresh store has to decrypt the file rather than use cached state.
+	reopened := newTestStore(path, "correct horse")
+	for profile, want := range map[string]string{"default": "sh_default", "staging": "sh_staging"} {
+		got, err := reopened.Get(profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Get(%s) = %q, want %q", profile, got, want)
+		}
+	}
+
+	if err := reopened.Delete("staging"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Get("staging"); !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrCredentialNotFound", err)
+	}
+	if got, _ := reopened.Get("default"); got != "sh_default" {
+		t.Errorf("Delete removed other profiles, Get(default) = %q", got)
+	}
+}
+
+func TestEncryptedFileStoreWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+
+	if err := newTestStore(path, "correct horse").Set("default", "sh_default"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newTestStore(path, "battery staple").Get("default"); err == nil {
+		t.Fatal("Get with the wrong passphrase succeeded")
+	}
+
+	if err := newTestStore(path, "").Set("default", "sh_other"); err == nil {
+		t.Fatal("Set with an empty passphrase succeeded")
+	}
+}
@@ -2,57 +2,179 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"os/user"
 	"path/filepath"
+	"sort"
 
 	"github.com/pelletier/go-toml/v2"
 )
 
+const DefaultProfileName = "default"
+
 type Profile struct {
-	Token string `toml:"token"`
-	Team  string `toml:"team"`
+	// Name is the key the profile is stored under in the profile file.
+	Name string `toml:"-"`
+
+	// Token is only set for profiles using plaintext credentials, see
+	// LoadToken for reading the token regardless of where it's stored.
+	Token string `toml:"token,omitempty"`
+	Team  string `toml:"team,omitempty"`
+	// Credentials names the store holding the token, empty for plaintext.
+	Credentials string `toml:"credentials,omitempty"`
+	// APIURL and WebURL override the production Sailhouse URLs, e.g. to
+	// target staging or a local emulator.
+	APIURL string `toml:"api_url,omitempty"`
+	WebURL string `toml:"web_url,omitempty"`
 }
 
-func LoadProfile() Profile {
-	profile := Profile{}
+// ProfileFile is the on-disk layout of ~/.sailhouse/profile.toml.
+type ProfileFile struct {
+	Current  string             `toml:"current,omitempty"`
+	Profiles map[string]Profile `toml:"profiles"`
+
+	// Files written before named profiles existed stored a single profile
+	// at the top level. It's migrated into the default profile on load.
+	LegacyToken string `toml:"token,omitempty"`
+	LegacyTeam  string `toml:"team,omitempty"`
+}
 
+func ProfilePath() string {
 	usr, _ := user.Current()
 	dir := usr.HomeDir
-	profileBytes, err := os.ReadFile(filepath.Join(dir, "/.sailhouse/profile.toml"))
+
+	return filepath.Join(dir, "/.sailhouse/profile.toml")
+}
+
+func LoadProfileFile() (ProfileFile, error) {
+	file := ProfileFile{}
+
+	profileBytes, err := os.ReadFile(ProfilePath())
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			profile.SaveProfile()
-		} else {
-			panic(err)
+		if !errors.Is(err, os.ErrNotExist) {
+			return file, err
 		}
 	}
 
-	err = toml.Unmarshal(profileBytes, &profile)
+	err = toml.Unmarshal(profileBytes, &file)
 	if err != nil {
-		panic(err)
+		return file, fmt.Errorf("reading %s: %w", ProfilePath(), err)
 	}
 
-	return profile
-}
+	if file.Profiles == nil {
+		file.Profiles = map[string]Profile{}
+	}
 
-func (p *Profile) SaveProfile() {
-	usr, _ := user.Current()
-	dir := usr.HomeDir
+	if file.LegacyToken != "" || file.LegacyTeam != "" {
+		if _, ok := file.Profiles[DefaultProfileName]; !ok {
+			file.Profiles[DefaultProfileName] = Profile{
+				Token: file.LegacyToken,
+				Team:  file.LegacyTeam,
+			}
+		}
+		file.LegacyToken = ""
+		file.LegacyTeam = ""
+	}
+
+	for name, profile := range file.Profiles {
+		profile.Name = name
+		file.Profiles[name] = profile
+	}
 
-	profileBytes, err := toml.Marshal(p)
+	return file, nil
+}
+
+func (f *ProfileFile) Save() error {
+	profileBytes, err := toml.Marshal(f)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	// ensure the `~/.sailhouse` directory exists
-	err = os.MkdirAll(filepath.Join(dir, "/.sailhouse"), 0700)
+	err = os.MkdirAll(filepath.Dir(ProfilePath()), 0700)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	err = os.WriteFile(filepath.Join(dir, "/.sailhouse/profile.toml"), profileBytes, 0600)
+	return os.WriteFile(ProfilePath(), profileBytes, 0600)
+}
+
+// ActiveName resolves the profile to use. An explicit name, from --profile or
+// SAILHOUSE_PROFILE, wins over the profile selected with `profile use`.
+func (f *ProfileFile) ActiveName(override string) string {
+	if override != "" {
+		return override
+	}
+
+	if f.Current != "" {
+		return f.Current
+	}
+
+	return DefaultProfileName
+}
+
+func (f *ProfileFile) Names() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (f *ProfileFile) Use(name string) error {
+	if _, ok := f.Profiles[name]; !ok {
+		return fmt.Errorf("profile %s does not exist", name)
+	}
+
+	f.Current = name
+	return nil
+}
+
+func (f *ProfileFile) Delete(name string) error {
+	if _, ok := f.Profiles[name]; !ok {
+		return fmt.Errorf("profile %s does not exist", name)
+	}
+
+	delete(f.Profiles, name)
+	if f.Current == name {
+		f.Current = ""
+	}
+
+	return nil
+}
+
+// LoadProfile returns the named profile, or the active one when name is
+// empty. Profiles that don't exist yet are returned empty and are created
+// by SaveProfile.
+func LoadProfile(name string) (Profile, error) {
+	file, err := LoadProfileFile()
+	if err != nil {
+		return Profile{}, err
+	}
+
+	name = file.ActiveName(name)
+
+	profile, ok := file.Profiles[name]
+	if !ok {
+		profile = Profile{Name: name}
+	}
+
+	return profile, nil
+}
+
+func (p *Profile) SaveProfile() error {
+	file, err := LoadProfileFile()
 	if err != nil {
-		panic(err)
+		return err
 	}
+
+	if p.Name == "" {
+		p.Name = file.ActiveName("")
+	}
+
+	file.Profiles[p.Name] = *p
+	return file.Save()
 }
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ProjectDir      = ".sailhouse"
+	ProjectFileName = "config.yaml"
+)
+
+// Project is the per-repository config in .sailhouse/config.yaml. It pins a
+// repository to an app so commands run inside it don't need --app.
+type Project struct {
+	App   string `yaml:"app,omitempty" json:"app,omitempty"`
+	Team  string `yaml:"team,omitempty" json:"team,omitempty"`
+	Topic string `yaml:"topic,omitempty" json:"topic,omitempty"`
+	// Schema is the schema file used by plan and apply, relative to the
+	// project root.
+	Schema string `yaml:"schema,omitempty" json:"schema,omitempty"`
+	// Env holds overrides keyed by profile name, applied on top of the
+	// settings above when that profile is active.
+	Env map[string]ProjectEnv `yaml:"env,omitempty" json:"env,omitempty"`
+
+	// Path is where the project config was loaded from.
+	Path string `yaml:"-" json:"path"`
+}
+
+type ProjectEnv struct {
+	App    string `yaml:"app,omitempty" json:"app,omitempty"`
+	Team   string `yaml:"team,omitempty" json:"team,omitempty"`
+	Topic  string `yaml:"topic,omitempty" json:"topic,omitempty"`
+	APIURL string `yaml:"api_url,omitempty" json:"api_url,omitempty"`
+}
+
+// FindProject looks for .sailhouse/config.yaml in dir and each of its
+// parents, returning nil when there isn't one.
+func FindProject(dir string) (*Project, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		path := filepath.Join(dir, ProjectDir, ProjectFileName)
+		if _, err := os.Stat(path); err == nil {
+			return LoadProject(path)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+func LoadProject(path string) (*Project, error) {
+	projectBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	project := &Project{}
+	if err := yaml.Unmarshal(projectBytes, project); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	project.Path = path
+
+	return project, nil
+}
+
+// NewProjectTeam returns the team init links a directory to: flag (--team),
+// then SAILHOUSE_TEAM, then the profile's team. A parent project's team is
+// skipped, since it belongs to that project.
+func NewProjectTeam(flag string, profile Profile) string {
+	if flag != "" {
+		return flag
+	}
+
+	if team := os.Getenv("SAILHOUSE_TEAM"); team != "" {
+		return team
+	}
+
+	return profile.Team
+}
+
+// Root is the directory containing the .sailhouse directory.
+func (p *Project) Root() string {
+	return filepath.Dir(filepath.Dir(p.Path))
+}
+
+// SchemaPath resolves the schema file relative to the project root.
+func (p *Project) SchemaPath() string {
+	if p.Schema == "" || filepath.IsAbs(p.Schema) {
+		return p.Schema
+	}
+
+	return filepath.Join(p.Root(), p.Schema)
+}
+
+// Resolve merges the overrides for the given profile into the project
+// settings.
+func (p *Project) Resolve(profile string) ProjectEnv {
+	resolved := ProjectEnv{
+		App:   p.App,
+		Team:  p.Team,
+		Topic: p.Topic,
+	}
+
+	env, ok := p.Env[profile]
+	if !ok {
+		return resolved
+	}
+
+	if env.App != "" {
+		resolved.App = env.App
+	}
+	if env.Team != "" {
+		resolved.Team = env.Team
+	}
+	if env.Topic != "" {
+		resolved.Topic = env.Topic
+	}
+	resolved.APIURL = env.APIURL
+
+	return resolved
+}
+
+// RenameApp points settings using the app at its new slug, reporting whether
+// anything changed. Settings pinned to a different team are left alone.
+func (p *Project) RenameApp(team, oldSlug, newSlug string) bool {
+	renamed := false
+
+	if p.App == oldSlug && (p.Team == "" || p.Team == team) {
+		p.App = newSlug
+		renamed = true
+	}
+
+	for name, env := range p.Env {
+		envTeam := env.Team
+		if envTeam == "" {
+			envTeam = p.Team
+		}
+
+		if env.App == oldSlug && (envTeam == "" || envTeam == team) {
+			env.App = newSlug
+			p.Env[name] = env
+			renamed = true
+		}
+	}
+
+	return renamed
+}
+
+func (p *Project) Save() error {
+	projectBytes, err := yaml.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.Path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(p.Path, projectBytes, 0644)
+}
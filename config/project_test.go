@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindProject(t *testing.T) {
+	root := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(root, ProjectDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ProjectDir, ProjectFileName), []byte("app: orders\nteam: acme\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	nested := filepath.Join(root, "services", "billing")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{root, nested} {
+		project, err := FindProject(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if project == nil {
+			t.Fatalf("FindProject(%s) found nothing", dir)
+		}
+
+		if project.App != "orders" || project.Team != "acme" {
+			t.Errorf("FindProject(%s) = %+v, want app orders in team acme", dir, project)
+		}
+
+		if project.Root() != root {
+			t.Errorf("Root() = %s, want %s", project.Root(), root)
+		}
+	}
+}
+
+func TestFindProjectMissing(t *testing.T) {
+	project, err := FindProject(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if project != nil {
+		t.Errorf("FindProject outside a project = %+v, want nil", project)
+	}
+}
+
+func TestNewProjectTeam(t *testing.T) {
+	// init uses this instead of viper's team, which includes a parent
+	// project's.
+	profile := Profile{Name: "default", Team: "personal"}
+
+	t.Setenv("SAILHOUSE_TEAM", "")
+	if team := NewProjectTeam("", profile); team != "personal" {
+		t.Errorf("team = %q, want the profile's personal", team)
+	}
+
+	t.Setenv("SAILHOUSE_TEAM", "from-env")
+	if team := NewProjectTeam("", profile); team != "from-env" {
+		t.Errorf("team = %q, want SAILHOUSE_TEAM's from-env", team)
+	}
+
+	if team := NewProjectTeam("from-flag", profile); team != "from-flag" {
+		t.Errorf("team = %q, want the flag's from-flag", team)
+	}
+}
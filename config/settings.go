@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/sailhouse/sailhouse/util"
+)
+
+const (
+	ScopeGlobal  = "global"
+	ScopeProject = "project"
+)
+
+// Setting describes a key that can be read and written with `sailhouse
+// config`. Global settings live in the active profile, project settings in
+// .sailhouse/config.yaml.
+type Setting struct {
+	Key         string
+	Description string
+	Scopes      []string
+	Validate    func(value string) error
+}
+
+func (s Setting) HasScope(scope string) bool {
+	for _, candidate := range s.Scopes {
+		if candidate == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DefaultScope is where set and unset write when no scope is given.
+func (s Setting) DefaultScope() string {
+	return s.Scopes[0]
+}
+
+var Settings = []Setting{
+	{Key: "team", Description: "Team to use", Scopes: []string{ScopeGlobal, ScopeProject}, Validate: validateSlug},
+	{Key: "api_url", Description: "Base URL of the Sailhouse API", Scopes: []string{ScopeGlobal}, Validate: validateURL},
+	{Key: "web_url", Description: "Base URL of the Sailhouse dashboard", Scopes: []string{ScopeGlobal}, Validate: validateURL},
+	{Key: "app", Description: "App the project is linked to", Scopes: []string{ScopeProject}, Validate: validateSlug},
+	{Key: "topic", Description: "Default topic for publish, listen and subs list", Scopes: []string{ScopeProject}, Validate: validateSlug},
+	{Key: "schema", Description: "Schema file used by plan and apply, relative to the project root", Scopes: []string{ScopeProject}},
+}
+
+func LookupSetting(key string) (Setting, error) {
+	for _, setting := range Settings {
+		if setting.Key == key {
+			return setting, nil
+		}
+	}
+
+	keys := make([]string, 0, len(Settings))
+	for _, setting := range Settings {
+		keys = append(keys, setting.Key)
+	}
+	sort.Strings(keys)
+
+	return Setting{}, fmt.Errorf("unknown key %q, expected one of %v", key, keys)
+}
+
+func validateSlug(value string) error {
+	if !util.IsValidSlug(value) {
+		return fmt.Errorf("%q can only contain lowercase letters, numbers or dashes", value)
+	}
+
+	return nil
+}
+
+func validateURL(value string) error {
+	u, err := url.ParseRequestURI(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not a valid http(s) URL", value)
+	}
+
+	return nil
+}
+
+// GetSetting returns a global setting from the profile.
+func (p *Profile) GetSetting(key string) string {
+	switch key {
+	case "team":
+		return p.Team
+	case "api_url":
+		return p.APIURL
+	case "web_url":
+		return p.WebURL
+	}
+
+	return ""
+}
+
+// SetSetting writes a global setting to the profile, an empty value unsets
+// it. The profile still needs saving afterwards.
+func (p *Profile) SetSetting(key, value string) {
+	switch key {
+	case "team":
+		p.Team = value
+	case "api_url":
+		p.APIURL = value
+	case "web_url":
+		p.WebURL = value
+	}
+}
+
+// GetSetting returns a project setting, ignoring env overrides.
+func (p *Project) GetSetting(key string) string {
+	switch key {
+	case "team":
+		return p.Team
+	case "app":
+		return p.App
+	case "topic":
+		return p.Topic
+	case "schema":
+		return p.Schema
+	}
+
+	return ""
+}
+
+// SetSetting writes a project setting, an empty value unsets it.
+func (p *Project) SetSetting(key, value string) {
+	switch key {
+	case "team":
+		p.Team = value
+	case "app":
+		p.App = value
+	case "topic":
+		p.Topic = value
+	case "schema":
+		p.Schema = value
+	}
+}
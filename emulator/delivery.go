@@ -0,0 +1,219 @@
+package emulator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sailhouse/sailhouse/models"
+)
+
+const maxPushBackoff = time.Minute
+
+// publish fans an event out to every subscription on the topic whose filter
+// matches. Callers must hold s.mu.
+func (s *Server) publish(t *topic, event models.Event, visibleAt time.Time) {
+	for _, sub := range t.Subscriptions {
+		if !matchesFilter(event.Data, sub.FilterPath, sub.FilterValue) {
+			continue
+		}
+
+		sub.Deliveries = append(sub.Deliveries, &delivery{
+			Event:     event,
+			VisibleAt: visibleAt,
+		})
+	}
+
+	s.notify()
+}
+
+// matchesFilter reports whether the value at the dot-separated path in data
+// equals value. An empty value only requires the path to exist.
+func matchesFilter(data json.RawMessage, path, value string) bool {
+	if path == "" {
+		return true
+	}
+
+	var current any
+	if err := json.Unmarshal(data, &current); err != nil {
+		return false
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := current.(map[string]any)
+		if !ok {
+			return false
+		}
+
+		current, ok = obj[key]
+		if !ok {
+			return false
+		}
+	}
+
+	if value == "" {
+		return true
+	}
+
+	switch v := current.(type) {
+	case string:
+		return v == value
+	case nil:
+		return value == "null"
+	default:
+		encoded, err := json.Marshal(v)
+		return err == nil && string(encoded) == value
+	}
+}
+
+// receive hands out up to limit visible events from a pull subscription and
+// hides them for the visibility timeout. Events that have already used all
+// their attempts are dead-lettered instead. Callers must hold s.mu.
+func (s *Server) receive(sub *subscription, limit int, now time.Time) []models.Event {
+	events := []models.Event{}
+
+	for i := 0; i < len(sub.Deliveries) && len(events) < limit; i++ {
+		d := sub.Deliveries[i]
+		if d.VisibleAt.After(now) {
+			continue
+		}
+
+		if d.Attempts >= s.opts.MaxAttempts {
+			d.LastError = "not acknowledged within the visibility timeout"
+			sub.deadLetterDelivery(i, now)
+			i--
+			continue
+		}
+
+		d.Attempts++
+		d.VisibleAt = now.Add(s.opts.VisibilityTimeout)
+		events = append(events, d.Event)
+	}
+
+	return events
+}
+
+type pushAttempt struct {
+	team, app, topic, subscription string
+	endpoint                       string
+	event                          models.Event
+}
+
+func (s *Server) dispatchPush(ctx context.Context) {
+	now := time.Now()
+	attempts := []pushAttempt{}
+
+	s.mu.Lock()
+	for _, t := range s.state.Teams {
+		for _, a := range t.Apps {
+			for _, tp := range a.Topics {
+				for _, sub := range tp.Subscriptions {
+					if sub.Type != "push" || sub.Paused {
+						continue
+					}
+
+					for _, d := range sub.Deliveries {
+						if d.VisibleAt.After(now) {
+							continue
+						}
+
+						// Hide the delivery while the request is in flight.
+						d.Attempts++
+						d.VisibleAt = now.Add(s.opts.HTTPClient.Timeout + time.Minute)
+						attempts = append(attempts, pushAttempt{t.Slug, a.Slug, tp.Slug, sub.Slug, sub.Endpoint, d.Event})
+					}
+				}
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	for _, attempt := range attempts {
+		go s.push(ctx, attempt)
+	}
+}
+
+func (s *Server) push(ctx context.Context, attempt pushAttempt) {
+	pushErr := deliverPush(ctx, s.opts.HTTPClient, attempt.endpoint, attempt.event)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The subscription may have been deleted while the request was in flight.
+	t := s.state.team(attempt.team)
+	if t == nil {
+		return
+	}
+	a := t.app(attempt.app)
+	if a == nil {
+		return
+	}
+	tp := a.topic(attempt.topic)
+	if tp == nil {
+		return
+	}
+	sub := tp.subscription(attempt.subscription)
+	if sub == nil {
+		return
+	}
+
+	i, d := sub.deliveryFor(attempt.event.ID)
+	if d == nil {
+		return
+	}
+
+	now := time.Now()
+	if pushErr == nil {
+		sub.Deliveries = append(sub.Deliveries[:i], sub.Deliveries[i+1:]...)
+		s.opts.Logger.Printf("delivered %s to %s/%s", attempt.event.ID, attempt.topic, attempt.subscription)
+		s.saveLocked()
+		return
+	}
+
+	d.LastError = pushErr.Error()
+	if d.Attempts >= s.opts.MaxAttempts {
+		sub.deadLetterDelivery(i, now)
+		s.opts.Logger.Printf("dead-lettered %s on %s/%s after %d attempts: %s", attempt.event.ID, attempt.topic, attempt.subscription, d.Attempts, pushErr)
+	} else {
+		backoff := time.Second << (d.Attempts - 1)
+		if backoff > maxPushBackoff {
+			backoff = maxPushBackoff
+		}
+		d.VisibleAt = now.Add(backoff)
+		s.opts.Logger.Printf("failed to deliver %s to %s/%s, retrying in %s: %s", attempt.event.ID, attempt.topic, attempt.subscription, backoff, pushErr)
+	}
+
+	s.saveLocked()
+}
+
+func deliverPush(ctx context.Context, httpClient *http.Client, endpoint string, event models.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Sailhouse-Event-Id", event.ID)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+
+	return nil
+}
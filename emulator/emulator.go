@@ -0,0 +1,180 @@
+// Package emulator implements an in-process stand-in for the Sailhouse API.
+//
+// It serves the same REST endpoints the api package calls, plus publishing,
+// pulling, acknowledging and push delivery with retries and dead-lettering,
+// so the CLI can be used and tested without network access.
+package emulator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sailhouse/sailhouse/models"
+	"github.com/sailhouse/sailhouse/publicid"
+)
+
+type Options struct {
+	// Team and App are created on start if they don't already exist.
+	Team string
+	App  string
+	// DataFile persists state between runs. Empty keeps state in memory.
+	DataFile string
+	// MaxAttempts is how many times an event is delivered to a subscription
+	// before it's moved to the subscription's dead letters.
+	MaxAttempts int
+	// VisibilityTimeout is how long a pulled event stays hidden from other
+	// pulls before it's redelivered if it isn't acknowledged.
+	VisibilityTimeout time.Duration
+	// HTTPClient is used for push deliveries.
+	HTTPClient *http.Client
+	Logger     *log.Logger
+}
+
+type Server struct {
+	opts Options
+
+	mu    sync.Mutex
+	state *state
+	// wake is closed and replaced whenever new deliveries become available,
+	// releasing any long-polling pulls.
+	wake chan struct{}
+}
+
+func New(opts Options) (*Server, error) {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+
+	if opts.VisibilityTimeout <= 0 {
+		opts.VisibilityTimeout = 30 * time.Second
+	}
+
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	if opts.Logger == nil {
+		opts.Logger = log.New(io.Discard, "", 0)
+	}
+
+	s := &Server{
+		opts:  opts,
+		state: &state{},
+		wake:  make(chan struct{}),
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	s.seed()
+
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Run delivers events to push subscriptions until ctx is cancelled.
+func (s *Server) Run(ctx context.Context) {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.dispatchPush(ctx)
+		}
+	}
+}
+
+func (s *Server) seed() {
+	if s.opts.Team == "" {
+		return
+	}
+
+	t := s.state.team(s.opts.Team)
+	if t == nil {
+		t = &team{Team: models.Team{ID: newID(), Slug: s.opts.Team}}
+		s.state.Teams = append(s.state.Teams, t)
+	}
+
+	if s.opts.App != "" && t.app(s.opts.App) == nil {
+		t.Apps = append(t.Apps, &app{App: models.App{ID: newID(), Slug: s.opts.App}})
+	}
+}
+
+func (s *Server) load() error {
+	if s.opts.DataFile == "" {
+		return nil
+	}
+
+	stateBytes, err := os.ReadFile(s.opts.DataFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	if err := json.Unmarshal(stateBytes, s.state); err != nil {
+		return fmt.Errorf("reading emulator state from %s: %w", s.opts.DataFile, err)
+	}
+
+	return nil
+}
+
+// save persists the state if a data file is configured. Callers must hold
+// s.mu, except during New.
+func (s *Server) save() error {
+	if s.opts.DataFile == "" {
+		return nil
+	}
+
+	stateBytes, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.opts.DataFile), 0700); err != nil {
+		return err
+	}
+
+	// Write then rename so a crash never leaves a half-written file behind.
+	tmp := s.opts.DataFile + ".tmp"
+	if err := os.WriteFile(tmp, stateBytes, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.opts.DataFile)
+}
+
+// saveLocked is save for handlers, which log rather than fail the request
+// when persisting goes wrong.
+func (s *Server) saveLocked() {
+	if err := s.save(); err != nil {
+		s.opts.Logger.Printf("failed to save state: %s", err)
+	}
+}
+
+// notify wakes long-polling pulls. Callers must hold s.mu.
+func (s *Server) notify() {
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
+
+func newID() string {
+	return publicid.Must()
+}
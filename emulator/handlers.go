@@ -0,0 +1,1075 @@
+package emulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sailhouse/sailhouse/api"
+	"github.com/sailhouse/sailhouse/models"
+	"github.com/sailhouse/sailhouse/util"
+)
+
+const (
+	appPath          = "/teams/{team}/apps/{app}"
+	topicPath        = appPath + "/topics/{topic}"
+	subscriptionPath = topicPath + "/subscriptions/{subscription}"
+	maxPullWait      = 60 * time.Second
+	maxPageSize      = 100
+	appTokenPrefix   = "sh_app_"
+)
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /user/auth/token", s.handleAuthToken)
+	mux.HandleFunc("DELETE /user/auth/token", s.authed(s.handleRevokeAuthToken))
+	mux.HandleFunc("GET /user", s.authed(s.handleGetUser))
+
+	mux.HandleFunc("GET /teams", s.authed(s.handleListTeams))
+	mux.HandleFunc("GET /teams/{team}/apps", s.authed(s.handleListApps))
+	mux.HandleFunc("POST "+appPath, s.authed(s.handleCreateApp))
+	mux.HandleFunc("GET "+appPath, s.authed(s.handleGetApp))
+	mux.HandleFunc("PATCH "+appPath, s.authed(s.handleUpdateApp))
+	mux.HandleFunc("DELETE "+appPath, s.authed(s.handleDeleteApp))
+	mux.HandleFunc("GET "+appPath+"/usage", s.authed(s.handleAppUsage))
+
+	mux.HandleFunc("GET "+appPath+"/tokens", s.authed(s.handleListTokens))
+	mux.HandleFunc("POST "+appPath+"/tokens", s.authed(s.handleCreateToken))
+	mux.HandleFunc("PATCH "+appPath+"/tokens/{token}", s.authed(s.handleUpdateToken))
+	mux.HandleFunc("DELETE "+appPath+"/tokens/{token}", s.authed(s.handleDeleteToken))
+
+	mux.HandleFunc("GET "+appPath+"/topics", s.authed(s.handleListTopics))
+	mux.HandleFunc("POST "+appPath+"/topics", s.authed(s.handleCreateTopic))
+	mux.HandleFunc("POST "+topicPath, s.authed(s.handleCreateTopic))
+	mux.HandleFunc("GET "+topicPath, s.authed(s.handleGetTopic))
+	mux.HandleFunc("PATCH "+topicPath, s.authed(s.handleUpdateTopic))
+	mux.HandleFunc("DELETE "+topicPath, s.authed(s.handleDeleteTopic))
+	mux.HandleFunc("POST "+topicPath+"/events", s.authed(s.handlePublish))
+
+	mux.HandleFunc("GET "+topicPath+"/subscriptions", s.authed(s.handleListSubscriptions))
+	mux.HandleFunc("POST "+topicPath+"/subscriptions", s.authed(s.handleCreateSubscription))
+	mux.HandleFunc("GET "+subscriptionPath, s.authed(s.handleGetSubscription))
+	mux.HandleFunc("PATCH "+subscriptionPath, s.authed(s.handleUpdateSubscription))
+	mux.HandleFunc("DELETE "+subscriptionPath, s.authed(s.handleDeleteSubscription))
+
+	mux.HandleFunc("GET "+subscriptionPath+"/events", s.authed(s.handlePull))
+	mux.HandleFunc("POST "+subscriptionPath+"/events/{event}", s.authed(s.handleAck))
+
+	mux.HandleFunc("GET "+subscriptionPath+"/dead-letters", s.authed(s.handleListDeadLetters))
+	mux.HandleFunc("DELETE "+subscriptionPath+"/dead-letters", s.authed(s.handlePurgeDeadLetters))
+	mux.HandleFunc("POST "+subscriptionPath+"/dead-letters/replay", s.authed(s.handleReplayDeadLetters))
+	mux.HandleFunc("GET "+subscriptionPath+"/dead-letters/{deadLetter}", s.authed(s.handleGetDeadLetter))
+
+	return s.logged(mux)
+}
+
+func (s *Server) logged(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.opts.Logger.Printf("%s %s", r.Method, r.URL.RequestURI())
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authed rejects requests without a token. App tokens are checked against
+// their app, scope and expiry; any other token is accepted unless it's been
+// revoked, as the emulator has no notion of users.
+func (s *Server) authed(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		value := r.Header.Get("Authorization")
+		if value == "" {
+			writeError(w, http.StatusUnauthorized, "missing Authorization header")
+			return
+		}
+
+		s.mu.Lock()
+		t, a, tk := s.state.appToken(value)
+		if tk == nil && (strings.HasPrefix(value, appTokenPrefix) || slices.Contains(s.state.RevokedTokens, value)) {
+			s.mu.Unlock()
+			writeError(w, http.StatusUnauthorized, "token revoked or invalid")
+			return
+		}
+		if tk != nil {
+			now := time.Now()
+			if tk.ExpiresAt != nil && !tk.ExpiresAt.After(now) {
+				s.mu.Unlock()
+				writeError(w, http.StatusUnauthorized, "token expired")
+				return
+			}
+
+			if r.PathValue("team") != t.Slug || r.PathValue("app") != a.Slug || !scopeAllows(tk.Scope, r) {
+				s.mu.Unlock()
+				writeError(w, http.StatusForbidden, fmt.Sprintf("token can't be used for %s %s", r.Method, r.URL.Path))
+				return
+			}
+
+			tk.LastUsedAt = &now
+		}
+		s.mu.Unlock()
+
+		next(w, r)
+	}
+}
+
+// scopeAllows reports whether a token with the given scope may make the
+// request. Publish tokens may only publish events, read tokens may only read.
+func scopeAllows(scope string, r *http.Request) bool {
+	switch scope {
+	case "publish":
+		return r.Method == http.MethodPost && r.PathValue("topic") != "" && r.PathValue("subscription") == "" && strings.HasSuffix(r.URL.Path, "/events")
+	case "read":
+		return r.Method == http.MethodGet
+	}
+
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON body: %s", err))
+		return false
+	}
+
+	return true
+}
+
+// The lookup helpers resolve path segments into state, writing a 404 and
+// returning nil when something doesn't exist. Callers must hold s.mu.
+
+func (s *Server) lookupTeam(w http.ResponseWriter, r *http.Request) *team {
+	t := s.state.team(r.PathValue("team"))
+	if t == nil {
+		writeError(w, http.StatusNotFound, "team not found")
+	}
+
+	return t
+}
+
+func (s *Server) lookupApp(w http.ResponseWriter, r *http.Request) *app {
+	t := s.lookupTeam(w, r)
+	if t == nil {
+		return nil
+	}
+
+	a := t.app(r.PathValue("app"))
+	if a == nil {
+		writeError(w, http.StatusNotFound, "app not found")
+	}
+
+	return a
+}
+
+func (s *Server) lookupTopic(w http.ResponseWriter, r *http.Request) *topic {
+	a := s.lookupApp(w, r)
+	if a == nil {
+		return nil
+	}
+
+	t := a.topic(r.PathValue("topic"))
+	if t == nil {
+		writeError(w, http.StatusNotFound, "topic not found")
+	}
+
+	return t
+}
+
+func (s *Server) lookupSubscription(w http.ResponseWriter, r *http.Request) *subscription {
+	t := s.lookupTopic(w, r)
+	if t == nil {
+		return nil
+	}
+
+	sub := t.subscription(r.PathValue("subscription"))
+	if sub == nil {
+		writeError(w, http.StatusNotFound, "subscription not found")
+	}
+
+	return sub
+}
+
+func (s *Server) handleAuthToken(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("code") == "" {
+		writeError(w, http.StatusBadRequest, "missing code")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"token": "sh_emulator_" + newID()})
+}
+
+func (s *Server) handleRevokeAuthToken(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.RevokedTokens = append(s.state.RevokedTokens, r.Header.Get("Authorization"))
+	s.saveLocked()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleGetUser(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, models.User{ID: "local", Email: "dev@localhost", Name: "Local developer"})
+}
+
+func (s *Server) handleListTeams(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	teams := []models.Team{}
+	for _, t := range s.state.Teams {
+		teams = append(teams, t.Team)
+	}
+
+	writePage(w, r, teams)
+}
+
+func (s *Server) handleListApps(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.lookupTeam(w, r)
+	if t == nil {
+		return
+	}
+
+	apps := []models.App{}
+	for _, a := range t.Apps {
+		apps = append(apps, a.App)
+	}
+
+	writePage(w, r, apps)
+}
+
+func (s *Server) handleCreateApp(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.lookupTeam(w, r)
+	if t == nil {
+		return
+	}
+
+	slug := r.PathValue("app")
+	if !util.IsValidSlug(slug) {
+		writeError(w, http.StatusBadRequest, "slug can only contain lowercase letters, numbers or dashes")
+		return
+	}
+
+	if t.app(slug) != nil {
+		writeError(w, http.StatusConflict, "app already exists")
+		return
+	}
+
+	a := &app{App: models.App{ID: newID(), Slug: slug}}
+	t.Apps = append(t.Apps, a)
+	s.saveLocked()
+
+	writeJSON(w, http.StatusCreated, a.App)
+}
+
+func (s *Server) handleGetApp(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := s.lookupApp(w, r)
+	if a == nil {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, a.App)
+}
+
+func (s *Server) handleUpdateApp(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Slug *string `json:"slug"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.lookupTeam(w, r)
+	if t == nil {
+		return
+	}
+
+	a := s.lookupApp(w, r)
+	if a == nil {
+		return
+	}
+
+	if body.Slug != nil && *body.Slug != a.Slug {
+		if !util.IsValidSlug(*body.Slug) {
+			writeError(w, http.StatusBadRequest, "slug can only contain lowercase letters, numbers or dashes")
+			return
+		}
+
+		if t.app(*body.Slug) != nil {
+			writeError(w, http.StatusConflict, "app already exists")
+			return
+		}
+
+		a.Slug = *body.Slug
+	}
+	s.saveLocked()
+
+	writeJSON(w, http.StatusOK, a.App)
+}
+
+func (s *Server) handleDeleteApp(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.lookupTeam(w, r)
+	if t == nil {
+		return
+	}
+
+	if !t.deleteApp(r.PathValue("app")) {
+		writeError(w, http.StatusNotFound, "app not found")
+		return
+	}
+	s.saveLocked()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleAppUsage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := s.lookupApp(w, r)
+	if a == nil {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.AppUsage{AppID: a.ID, Count: a.EventCount})
+}
+
+func (s *Server) handleListTokens(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := s.lookupApp(w, r)
+	if a == nil {
+		return
+	}
+
+	tokens := []models.TokenPreview{}
+	for _, tk := range a.Tokens {
+		tokens = append(tokens, tk.TokenPreview)
+	}
+
+	writePage(w, r, tokens)
+}
+
+func (s *Server) handleCreateToken(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name      string     `json:"name"`
+		Scope     string     `json:"scope"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	// Older clients send no body at all.
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON body: %s", err))
+		return
+	}
+
+	switch body.Scope {
+	case "":
+		body.Scope = "full"
+	case "full", "publish", "read":
+	default:
+		writeError(w, http.StatusBadRequest, "scope must be full, publish or read")
+		return
+	}
+
+	now := time.Now().UTC()
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(now) {
+		writeError(w, http.StatusBadRequest, "expires_at must be in the future")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := s.lookupApp(w, r)
+	if a == nil {
+		return
+	}
+
+	value := appTokenPrefix + newID() + newID()
+	tk := &token{
+		TokenPreview: models.TokenPreview{
+			ID:        newID(),
+			Name:      body.Name,
+			Preview:   value[:12] + "...",
+			Scope:     body.Scope,
+			CreatedAt: now,
+			ExpiresAt: body.ExpiresAt,
+		},
+		Token: value,
+	}
+	a.Tokens = append(a.Tokens, tk)
+	s.saveLocked()
+
+	writeJSON(w, http.StatusCreated, tk)
+}
+
+func (s *Server) handleUpdateToken(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name      *string    `json:"name"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := s.lookupApp(w, r)
+	if a == nil {
+		return
+	}
+
+	tk := a.token(r.PathValue("token"))
+	if tk == nil {
+		writeError(w, http.StatusNotFound, "token not found")
+		return
+	}
+
+	if body.Name != nil {
+		tk.Name = *body.Name
+	}
+	if body.ExpiresAt != nil {
+		tk.ExpiresAt = body.ExpiresAt
+	}
+	s.saveLocked()
+
+	writeJSON(w, http.StatusOK, tk.TokenPreview)
+}
+
+func (s *Server) handleDeleteToken(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := s.lookupApp(w, r)
+	if a == nil {
+		return
+	}
+
+	if !a.deleteToken(r.PathValue("token")) {
+		writeError(w, http.StatusNotFound, "token not found")
+		return
+	}
+	s.saveLocked()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListTopics(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := s.lookupApp(w, r)
+	if a == nil {
+		return
+	}
+
+	topics := []models.Topic{}
+	for _, t := range a.Topics {
+		topics = append(topics, t.Topic)
+	}
+
+	writePage(w, r, topics)
+}
+
+func (s *Server) handleCreateTopic(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Slug      string `json:"slug"`
+		SchemaKey string `json:"schema_key"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	if slug := r.PathValue("topic"); slug != "" {
+		body.Slug = slug
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := s.lookupApp(w, r)
+	if a == nil {
+		return
+	}
+
+	if !util.IsValidSlug(body.Slug) {
+		writeError(w, http.StatusBadRequest, "slug can only contain lowercase letters, numbers or dashes")
+		return
+	}
+
+	if a.topic(body.Slug) != nil {
+		writeError(w, http.StatusConflict, "topic already exists")
+		return
+	}
+
+	t := &topic{Topic: models.Topic{ID: newID(), Slug: body.Slug, SchemaKey: body.SchemaKey}}
+	a.Topics = append(a.Topics, t)
+	s.saveLocked()
+
+	writeJSON(w, http.StatusCreated, t.Topic)
+}
+
+func (s *Server) handleGetTopic(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.lookupTopic(w, r)
+	if t == nil {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, t.Topic)
+}
+
+func (s *Server) handleUpdateTopic(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Slug      *string `json:"slug"`
+		SchemaKey *string `json:"schema_key"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := s.lookupApp(w, r)
+	if a == nil {
+		return
+	}
+
+	t := s.lookupTopic(w, r)
+	if t == nil {
+		return
+	}
+
+	if body.Slug != nil && *body.Slug != t.Slug {
+		if !util.IsValidSlug(*body.Slug) {
+			writeError(w, http.StatusBadRequest, "slug can only contain lowercase letters, numbers or dashes")
+			return
+		}
+
+		if a.topic(*body.Slug) != nil {
+			writeError(w, http.StatusConflict, "topic already exists")
+			return
+		}
+
+		t.Slug = *body.Slug
+	}
+
+	if body.SchemaKey != nil {
+		t.SchemaKey = *body.SchemaKey
+	}
+	s.saveLocked()
+
+	writeJSON(w, http.StatusOK, t.Topic)
+}
+
+func (s *Server) handleDeleteTopic(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := s.lookupApp(w, r)
+	if a == nil {
+		return
+	}
+
+	if !a.deleteTopic(r.PathValue("topic")) {
+		writeError(w, http.StatusNotFound, "topic not found")
+		return
+	}
+	s.saveLocked()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handlePublish(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Data     json.RawMessage   `json:"data"`
+		Metadata map[string]string `json:"metadata"`
+		SendAt   *time.Time        `json:"send_at"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	if len(body.Data) == 0 {
+		writeError(w, http.StatusBadRequest, "data is required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := s.lookupApp(w, r)
+	if a == nil {
+		return
+	}
+
+	t := s.lookupTopic(w, r)
+	if t == nil {
+		return
+	}
+
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if id, ok := t.IdempotencyKeys[idempotencyKey]; ok && idempotencyKey != "" {
+		writeJSON(w, http.StatusOK, map[string]string{"id": id})
+		return
+	}
+
+	now := time.Now()
+	event := models.Event{
+		ID:        newID(),
+		Data:      body.Data,
+		Metadata:  body.Metadata,
+		CreatedAt: now.UTC(),
+	}
+
+	visibleAt := now
+	if body.SendAt != nil {
+		visibleAt = *body.SendAt
+	}
+
+	s.publish(t, event, visibleAt)
+	a.EventCount++
+
+	if idempotencyKey != "" {
+		if t.IdempotencyKeys == nil {
+			t.IdempotencyKeys = map[string]string{}
+		}
+		t.IdempotencyKeys[idempotencyKey] = event.ID
+	}
+	s.saveLocked()
+
+	writeJSON(w, http.StatusCreated, map[string]string{"id": event.ID})
+}
+
+func (s *Server) handleListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.lookupTopic(w, r)
+	if t == nil {
+		return
+	}
+
+	subs := []models.Subscription{}
+	for _, sub := range t.Subscriptions {
+		subs = append(subs, sub.Subscription)
+	}
+
+	writePage(w, r, subs)
+}
+
+func (s *Server) handleCreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Slug        string `json:"slug"`
+		Type        string `json:"type"`
+		Endpoint    string `json:"endpoint"`
+		SchemaKey   string `json:"schema_key"`
+		FilterPath  string `json:"filter_path"`
+		FilterValue string `json:"filter_value"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	if !util.IsValidSlug(body.Slug) {
+		writeError(w, http.StatusBadRequest, "slug can only contain lowercase letters, numbers or dashes")
+		return
+	}
+
+	if body.Type != "pull" && body.Type != "push" {
+		writeError(w, http.StatusBadRequest, "type must be pull or push")
+		return
+	}
+
+	if body.Type == "push" && body.Endpoint == "" {
+		writeError(w, http.StatusBadRequest, "push subscriptions require an endpoint")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.lookupTopic(w, r)
+	if t == nil {
+		return
+	}
+
+	if t.subscription(body.Slug) != nil {
+		writeError(w, http.StatusConflict, "subscription already exists")
+		return
+	}
+
+	sub := &subscription{Subscription: models.Subscription{
+		ID:          newID(),
+		TopicID:     t.ID,
+		Slug:        body.Slug,
+		Type:        body.Type,
+		Endpoint:    body.Endpoint,
+		SchemaKey:   body.SchemaKey,
+		FilterPath:  body.FilterPath,
+		FilterValue: body.FilterValue,
+	}}
+	t.Subscriptions = append(t.Subscriptions, sub)
+	s.saveLocked()
+
+	writeJSON(w, http.StatusCreated, sub.Subscription)
+}
+
+func (s *Server) handleGetSubscription(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := s.lookupSubscription(w, r)
+	if sub == nil {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sub.Subscription)
+}
+
+func (s *Server) handleUpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Type        *string `json:"type"`
+		Endpoint    *string `json:"endpoint"`
+		SchemaKey   *string `json:"schema_key"`
+		FilterPath  *string `json:"filter_path"`
+		FilterValue *string `json:"filter_value"`
+		Paused      *bool   `json:"paused"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := s.lookupSubscription(w, r)
+	if sub == nil {
+		return
+	}
+
+	// Validate the result before changing anything so a bad update leaves
+	// the subscription as it was.
+	updated := sub.Subscription
+	if body.Type != nil {
+		updated.Type = *body.Type
+	}
+	if body.Endpoint != nil {
+		updated.Endpoint = *body.Endpoint
+	}
+	if body.SchemaKey != nil {
+		updated.SchemaKey = *body.SchemaKey
+	}
+	if body.FilterPath != nil {
+		updated.FilterPath = *body.FilterPath
+	}
+	if body.FilterValue != nil {
+		updated.FilterValue = *body.FilterValue
+	}
+	if body.Paused != nil {
+		updated.Paused = *body.Paused
+	}
+
+	if updated.Type != "pull" && updated.Type != "push" {
+		writeError(w, http.StatusBadRequest, "type must be pull or push")
+		return
+	}
+
+	if updated.Type == "push" && updated.Endpoint == "" {
+		writeError(w, http.StatusBadRequest, "push subscriptions require an endpoint")
+		return
+	}
+
+	if updated.Type == "pull" {
+		updated.Endpoint = ""
+	}
+
+	sub.Subscription = updated
+	if !sub.Paused {
+		s.notify()
+	}
+	s.saveLocked()
+
+	writeJSON(w, http.StatusOK, sub.Subscription)
+}
+
+func (s *Server) handleDeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.lookupTopic(w, r)
+	if t == nil {
+		return
+	}
+
+	if !t.deleteSubscription(r.PathValue("subscription")) {
+		writeError(w, http.StatusNotFound, "subscription not found")
+		return
+	}
+	s.saveLocked()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handlePull(w http.ResponseWriter, r *http.Request) {
+	limit := queryInt(r, "limit", 10)
+	if limit == 0 {
+		limit = 10
+	}
+	wait := time.Duration(queryInt(r, "wait", 0)) * time.Second
+	if wait > maxPullWait {
+		wait = maxPullWait
+	}
+
+	deadline := time.Now().Add(wait)
+
+	for {
+		s.mu.Lock()
+		sub := s.lookupSubscription(w, r)
+		if sub == nil {
+			s.mu.Unlock()
+			return
+		}
+
+		if sub.Type != "pull" {
+			s.mu.Unlock()
+			writeError(w, http.StatusBadRequest, "only pull subscriptions can be pulled from")
+			return
+		}
+
+		// Paused subscriptions hold on to their events until resumed.
+		events := []models.Event{}
+		if !sub.Paused {
+			events = s.receive(sub, limit, time.Now())
+		}
+		if len(events) > 0 {
+			s.saveLocked()
+		}
+		wake := s.wake
+		s.mu.Unlock()
+
+		remaining := time.Until(deadline)
+		if len(events) > 0 || remaining <= 0 {
+			writeJSON(w, http.StatusOK, events)
+			return
+		}
+
+		// Events also become visible when scheduled sends or visibility
+		// timeouts pass, which don't wake pullers, so poll regularly too.
+		timer := time.NewTimer(min(remaining, time.Second))
+		select {
+		case <-r.Context().Done():
+			timer.Stop()
+			return
+		case <-wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+func (s *Server) handleAck(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := s.lookupSubscription(w, r)
+	if sub == nil {
+		return
+	}
+
+	i, d := sub.deliveryFor(r.PathValue("event"))
+	if d == nil {
+		writeError(w, http.StatusNotFound, "event not found or already acknowledged")
+		return
+	}
+
+	sub.Deliveries = append(sub.Deliveries[:i], sub.Deliveries[i+1:]...)
+	s.saveLocked()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := s.lookupSubscription(w, r)
+	if sub == nil {
+		return
+	}
+
+	writePage(w, r, sub.DeadLetters)
+}
+
+func (s *Server) handleGetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := s.lookupSubscription(w, r)
+	if sub == nil {
+		return
+	}
+
+	deadLetter := sub.deadLetter(r.PathValue("deadLetter"))
+	if deadLetter == nil {
+		writeError(w, http.StatusNotFound, "dead letter not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deadLetter)
+}
+
+func (s *Server) handleReplayDeadLetters(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		IDs []string `json:"ids"`
+		All bool     `json:"all"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := s.lookupSubscription(w, r)
+	if sub == nil {
+		return
+	}
+
+	selected := map[string]bool{}
+	for _, id := range body.IDs {
+		selected[id] = true
+	}
+
+	now := time.Now()
+	remaining := []models.DeadLetter{}
+	replayed := 0
+	for _, deadLetter := range sub.DeadLetters {
+		if !body.All && !selected[deadLetter.ID] {
+			remaining = append(remaining, deadLetter)
+			continue
+		}
+
+		sub.Deliveries = append(sub.Deliveries, &delivery{
+			Event: models.Event{
+				ID:        deadLetter.EventID,
+				Data:      deadLetter.Data,
+				Metadata:  deadLetter.Metadata,
+				CreatedAt: deadLetter.FailedAt,
+			},
+			VisibleAt: now,
+		})
+		replayed++
+	}
+
+	if !body.All && replayed == 0 {
+		writeError(w, http.StatusNotFound, "no matching dead letters")
+		return
+	}
+
+	sub.DeadLetters = remaining
+	s.notify()
+	s.saveLocked()
+
+	writeJSON(w, http.StatusOK, map[string]int{"replayed": replayed})
+}
+
+func (s *Server) handlePurgeDeadLetters(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := s.lookupSubscription(w, r)
+	if sub == nil {
+		return
+	}
+
+	purged := len(sub.DeadLetters)
+	sub.DeadLetters = []models.DeadLetter{}
+	s.saveLocked()
+
+	writeJSON(w, http.StatusOK, map[string]int{"purged": purged})
+}
+
+func queryInt(r *http.Request, name string, fallback int) int {
+	value, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil || value < 0 {
+		return fallback
+	}
+
+	return value
+}
+
+// writePage writes the page of items selected by the limit and cursor
+// parameters, setting the next cursor header when more remain. Cursors are
+// offsets, though clients treat them as opaque. The offset parameter is
+// still accepted for dead letters.
+func writePage[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	offset := queryInt(r, "offset", 0)
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		var err error
+		offset, err = strconv.Atoi(cursor)
+		if err != nil || offset < 0 {
+			writeError(w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+	}
+
+	limit := queryInt(r, "limit", maxPageSize)
+	if limit == 0 || limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	page := paginate(items, offset, limit)
+	if next := offset + len(page); next < len(items) {
+		w.Header().Set(api.NextCursorHeader, strconv.Itoa(next))
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+func paginate[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+
+	end := offset + limit
+	if limit == 0 || end > len(items) {
+		end = len(items)
+	}
+
+	return items[offset:end]
+}
@@ -0,0 +1,202 @@
+package emulator
+
+import (
+	"time"
+
+	"github.com/sailhouse/sailhouse/models"
+)
+
+// The emulator keeps everything in one tree rooted at state so it can be
+// persisted as a single JSON document.
+type state struct {
+	Teams []*team `json:"teams"`
+	// RevokedTokens are user tokens revoked with auth logout --revoke.
+	RevokedTokens []string `json:"revoked_tokens,omitempty"`
+}
+
+type team struct {
+	models.Team
+	Apps []*app `json:"apps"`
+}
+
+type app struct {
+	models.App
+	Topics []*topic `json:"topics"`
+	Tokens []*token `json:"tokens"`
+	// EventCount is the number of events published to the app's topics.
+	EventCount int `json:"event_count"`
+}
+
+type token struct {
+	models.TokenPreview
+	Token string `json:"token"`
+}
+
+type topic struct {
+	models.Topic
+	Subscriptions []*subscription `json:"subscriptions"`
+	// Event IDs already published under an idempotency key.
+	IdempotencyKeys map[string]string `json:"idempotency_keys,omitempty"`
+}
+
+type subscription struct {
+	models.Subscription
+	Deliveries  []*delivery         `json:"deliveries"`
+	DeadLetters []models.DeadLetter `json:"dead_letters"`
+}
+
+// delivery is an event waiting to be received and acknowledged by a single
+// subscription.
+type delivery struct {
+	Event     models.Event `json:"event"`
+	Attempts  int          `json:"attempts"`
+	VisibleAt time.Time    `json:"visible_at"`
+	LastError string       `json:"last_error,omitempty"`
+}
+
+func (s *state) team(slug string) *team {
+	for _, t := range s.Teams {
+		if t.Slug == slug {
+			return t
+		}
+	}
+
+	return nil
+}
+
+func (t *team) app(slug string) *app {
+	for _, a := range t.Apps {
+		if a.Slug == slug {
+			return a
+		}
+	}
+
+	return nil
+}
+
+func (t *team) deleteApp(slug string) bool {
+	for i, a := range t.Apps {
+		if a.Slug == slug {
+			t.Apps = append(t.Apps[:i], t.Apps[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *app) deleteToken(id string) bool {
+	for i, tk := range a.Tokens {
+		if tk.ID == id {
+			a.Tokens = append(a.Tokens[:i], a.Tokens[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *app) token(id string) *token {
+	for _, tk := range a.Tokens {
+		if tk.ID == id {
+			return tk
+		}
+	}
+
+	return nil
+}
+
+// appToken finds the app token with the given value, returning nil for
+// anything else, like user tokens.
+func (s *state) appToken(value string) (*team, *app, *token) {
+	for _, t := range s.Teams {
+		for _, a := range t.Apps {
+			for _, tk := range a.Tokens {
+				if tk.Token == value {
+					return t, a, tk
+				}
+			}
+		}
+	}
+
+	return nil, nil, nil
+}
+
+func (a *app) topic(slug string) *topic {
+	for _, t := range a.Topics {
+		if t.Slug == slug {
+			return t
+		}
+	}
+
+	return nil
+}
+
+func (a *app) deleteTopic(slug string) bool {
+	for i, t := range a.Topics {
+		if t.Slug == slug {
+			a.Topics = append(a.Topics[:i], a.Topics[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *topic) subscription(slug string) *subscription {
+	for _, s := range t.Subscriptions {
+		if s.Slug == slug {
+			return s
+		}
+	}
+
+	return nil
+}
+
+func (t *topic) deleteSubscription(slug string) bool {
+	for i, s := range t.Subscriptions {
+		if s.Slug == slug {
+			t.Subscriptions = append(t.Subscriptions[:i], t.Subscriptions[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *subscription) deliveryFor(eventID string) (int, *delivery) {
+	for i, d := range s.Deliveries {
+		if d.Event.ID == eventID {
+			return i, d
+		}
+	}
+
+	return -1, nil
+}
+
+func (s *subscription) deadLetter(id string) *models.DeadLetter {
+	for i := range s.DeadLetters {
+		if s.DeadLetters[i].ID == id {
+			return &s.DeadLetters[i]
+		}
+	}
+
+	return nil
+}
+
+// deadLetterDelivery moves a delivery that has run out of attempts onto the
+// subscription's dead letters.
+func (s *subscription) deadLetterDelivery(i int, now time.Time) {
+	d := s.Deliveries[i]
+	s.Deliveries = append(s.Deliveries[:i], s.Deliveries[i+1:]...)
+
+	s.DeadLetters = append(s.DeadLetters, models.DeadLetter{
+		ID:        newID(),
+		EventID:   d.Event.ID,
+		Data:      d.Event.Data,
+		Metadata:  d.Event.Metadata,
+		Attempts:  d.Attempts,
+		LastError: d.LastError,
+		FailedAt:  now,
+	})
+}
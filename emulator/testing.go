@@ -0,0 +1,22 @@
+package emulator
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// NewTestServer serves an in-memory emulator, with team "team" and app
+// "app", until the test finishes.
+func NewTestServer(t testing.TB) *httptest.Server {
+	t.Helper()
+
+	s, err := New(Options{Team: "team", App: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+
+	return srv
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type App struct {
 	ID   string `json:"id"`
 	Slug string `json:"slug"`
@@ -7,10 +9,17 @@ type App struct {
 
 type AppUsage struct {
 	AppID string `json:"app_id"`
-	Count int    `json:"count"`
+	// Count is the number of events published to the app.
+	Count int `json:"count"`
 }
 
 type TokenPreview struct {
 	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
 	Preview string `json:"preview"`
+	// Scope is "full", "publish" or "read".
+	Scope      string     `json:"scope"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
 }
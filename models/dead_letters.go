@@ -0,0 +1,16 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type DeadLetter struct {
+	ID        string            `json:"id"`
+	EventID   string            `json:"event_id"`
+	Data      json.RawMessage   `json:"data"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Attempts  int               `json:"attempts"`
+	LastError string            `json:"last_error"`
+	FailedAt  time.Time         `json:"failed_at"`
+}
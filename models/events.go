@@ -0,0 +1,13 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Event struct {
+	ID        string            `json:"id"`
+	Data      json.RawMessage   `json:"data"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
@@ -1,25 +1,25 @@
 package models
 
 type SchemaSubscriptionFilter struct {
-	Path  string `yaml:"path"`
-	Value string `yaml:"value"`
+	Path  string `yaml:"path,omitempty" json:"path,omitempty" validate:"required_with=Value"`
+	Value string `yaml:"value,omitempty" json:"value,omitempty"`
 }
 
 type SchemaSubscription struct {
-	Slug      string                   `yaml:"slug"`
-	TopicSlug string                   `yaml:"topic"`
-	Type      string                   `yaml:"type"`
-	Endpoint  string                   `yaml:"endpoint"`
-	Filter    SchemaSubscriptionFilter `yaml:"filter"`
+	Slug      string                   `yaml:"slug" json:"slug" validate:"required,slug"`
+	TopicSlug string                   `yaml:"topic" json:"topic" validate:"required,slug"`
+	Type      string                   `yaml:"type" json:"type" validate:"required,oneof=pull push"`
+	Endpoint  string                   `yaml:"endpoint,omitempty" json:"endpoint,omitempty" validate:"required_if=Type push,endpoint"`
+	Filter    SchemaSubscriptionFilter `yaml:"filter,omitempty" json:"filter,omitempty"`
 }
 
 type SchemaTopic struct {
-	Slug string `yaml:"slug"`
+	Slug string `yaml:"slug" json:"slug" validate:"required,slug"`
 }
 
 type Schema struct {
-	Key string `yaml:"key" validate:"max=12"`
+	Key string `yaml:"key,omitempty" json:"key,omitempty" validate:"max=12"`
 
-	Topics        []SchemaTopic        `yaml:"topics"`
-	Subscriptions []SchemaSubscription `yaml:"subscriptions"`
+	Topics        []SchemaTopic        `yaml:"topics" json:"topics" validate:"dive"`
+	Subscriptions []SchemaSubscription `yaml:"subscriptions" json:"subscriptions" validate:"dive"`
 }
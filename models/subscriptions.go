@@ -8,4 +8,8 @@ type Subscription struct {
 	FilterPath  string `json:"filter_path"`
 	FilterValue string `json:"filter_value"`
 	Endpoint    string `json:"endpoint"`
+	SchemaKey   string `json:"schema_key,omitempty"`
+	// Paused subscriptions keep receiving events but don't deliver them
+	// until resumed.
+	Paused bool `json:"paused"`
 }
@@ -1,6 +1,7 @@
 package models
 
 type Topic struct {
-	ID   string `json:"id"`
-	Slug string `json:"slug"`
+	ID        string `json:"id"`
+	Slug      string `json:"slug"`
+	SchemaKey string `json:"schema_key,omitempty"`
 }
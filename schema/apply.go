@@ -0,0 +1,81 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sailhouse/sailhouse/api"
+	"github.com/sailhouse/sailhouse/models"
+)
+
+// Apply carries out the changes in the plan in order, calling progress after
+// each one. It stops at the first failure and returns the changes that were
+// applied before it.
+func Apply(ctx context.Context, client *api.SailhouseClient, appID string, plan Plan, progress func(Change)) ([]Change, error) {
+	applied := []Change{}
+
+	for _, change := range plan.Changes {
+		if err := applyChange(ctx, client, appID, plan.Key, change); err != nil {
+			return applied, fmt.Errorf("%s %s %s: %w", change.Action, change.Kind, change.Name(), err)
+		}
+
+		applied = append(applied, change)
+		if progress != nil {
+			progress(change)
+		}
+	}
+
+	return applied, nil
+}
+
+func applyChange(ctx context.Context, client *api.SailhouseClient, appID, key string, change Change) error {
+	switch change.Kind {
+	case KindTopic:
+		switch change.Action {
+		case ActionCreate:
+			_, err := client.CreateTopic(ctx, appID, api.CreateTopic{Slug: change.Topic, SchemaKey: key})
+			return err
+		case ActionDelete:
+			return client.DeleteTopic(ctx, appID, change.Topic)
+		}
+	case KindSubscription:
+		switch change.Action {
+		case ActionCreate:
+			return createSubscription(ctx, client, appID, key, *change.Subscription)
+		case ActionUpdate:
+			return updateSubscription(ctx, client, appID, key, *change.Subscription)
+		case ActionDelete:
+			return client.DeleteSubscription(ctx, appID, change.Topic, change.Slug)
+		}
+	}
+
+	return fmt.Errorf("unsupported change %s %s", change.Action, change.Kind)
+}
+
+func createSubscription(ctx context.Context, client *api.SailhouseClient, appID, key string, sub models.SchemaSubscription) error {
+	_, err := client.CreateSubscription(ctx, appID, api.CreateSubscription{
+		Slug:        sub.Slug,
+		TopicSlug:   sub.TopicSlug,
+		Type:        sub.Type,
+		Endpoint:    sub.Endpoint,
+		SchemaKey:   key,
+		FilterPath:  sub.Filter.Path,
+		FilterValue: sub.Filter.Value,
+	})
+
+	return err
+}
+
+// updateSubscription changes the subscription in place rather than
+// recreating it, so events waiting to be delivered aren't lost.
+func updateSubscription(ctx context.Context, client *api.SailhouseClient, appID, key string, sub models.SchemaSubscription) error {
+	_, err := client.UpdateSubscription(ctx, appID, sub.TopicSlug, sub.Slug, api.UpdateSubscription{
+		Type:        &sub.Type,
+		Endpoint:    &sub.Endpoint,
+		SchemaKey:   &key,
+		FilterPath:  &sub.Filter.Path,
+		FilterValue: &sub.Filter.Value,
+	})
+
+	return err
+}
@@ -0,0 +1,143 @@
+package schema_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sailhouse/sailhouse/api"
+	"github.com/sailhouse/sailhouse/emulator"
+	"github.com/sailhouse/sailhouse/models"
+	"github.com/sailhouse/sailhouse/schema"
+)
+
+func TestDiffOrder(t *testing.T) {
+	live := api.GetSchema{
+		Topics: []models.Topic{
+			{ID: "t1", Slug: "orders", SchemaKey: "key"},
+			{ID: "t2", Slug: "legacy", SchemaKey: "key"},
+			{ID: "t3", Slug: "unmanaged"},
+		},
+		Subscriptions: []models.Subscription{
+			{ID: "s1", TopicID: "t1", Slug: "billing", Type: "pull", SchemaKey: "key"},
+			{ID: "s2", TopicID: "t1", Slug: "old", Type: "pull", SchemaKey: "key"},
+			{ID: "s3", TopicID: "t2", Slug: "audit", Type: "pull", SchemaKey: "key"},
+		},
+	}
+
+	desired := models.Schema{
+		Key:    "key",
+		Topics: []models.SchemaTopic{{Slug: "orders"}, {Slug: "invoices"}},
+		Subscriptions: []models.SchemaSubscription{
+			{Slug: "billing", TopicSlug: "orders", Type: "pull", Filter: models.SchemaSubscriptionFilter{Path: "kind", Value: "paid"}},
+			{Slug: "mailer", TopicSlug: "invoices", Type: "pull"},
+		},
+	}
+
+	plan := schema.Diff(desired, live, schema.DiffOptions{})
+
+	want := []string{
+		"create topic invoices",
+		// Subscription creates and updates follow the schema's order.
+		"update subscription orders/billing",
+		"create subscription invoices/mailer",
+		"delete subscription orders/old",
+		// legacy's subscriptions go with it, and unmanaged isn't owned by
+		// the schema.
+		"delete topic legacy",
+	}
+
+	if len(plan.Changes) != len(want) {
+		t.Fatalf("got %d changes %+v, want %d", len(plan.Changes), plan.Changes, len(want))
+	}
+
+	for i, change := range plan.Changes {
+		got := string(change.Action) + " " + string(change.Kind) + " " + change.Name()
+		if got != want[i] {
+			t.Errorf("change %d = %q, want %q", i, got, want[i])
+		}
+	}
+
+	pruned := schema.Diff(desired, live, schema.DiffOptions{Prune: true})
+	if last := pruned.Changes[len(pruned.Changes)-1]; last.Name() != "unmanaged" || last.Action != schema.ActionDelete {
+		t.Errorf("with prune the last change is %s %s, want delete unmanaged", last.Action, last.Name())
+	}
+}
+
+func apply(t *testing.T, client *api.SailhouseClient, desired models.Schema) schema.Plan {
+	t.Helper()
+	ctx := context.Background()
+
+	live, err := schema.FetchLive(ctx, client, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := schema.Diff(desired, live, schema.DiffOptions{})
+	applied, err := schema.Apply(ctx, client, "app", plan, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(applied) != len(plan.Changes) {
+		t.Fatalf("applied %d of %d changes", len(applied), len(plan.Changes))
+	}
+
+	return plan
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	srv := emulator.NewTestServer(t)
+	client := api.NewSailhouseClient("token", api.WithTeam("team"), api.WithBaseURL(srv.URL))
+
+	desired := models.Schema{
+		Key:    "key",
+		Topics: []models.SchemaTopic{{Slug: "orders"}, {Slug: "invoices"}},
+		Subscriptions: []models.SchemaSubscription{
+			{Slug: "billing", TopicSlug: "orders", Type: "pull"},
+			{Slug: "mailer", TopicSlug: "invoices", Type: "pull"},
+		},
+	}
+
+	if plan := apply(t, client, desired); len(plan.Changes) != 4 {
+		t.Fatalf("first apply made %d changes, want 4", len(plan.Changes))
+	}
+
+	// Applying the same schema again is a no-op.
+	if plan := apply(t, client, desired); plan.HasChanges() {
+		t.Fatalf("second apply made changes %+v", plan.Changes)
+	}
+
+	before, err := client.GetSubscription(ctx, "app", "orders", "billing")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desired.Topics = desired.Topics[:1]
+	desired.Subscriptions = []models.SchemaSubscription{
+		{Slug: "billing", TopicSlug: "orders", Type: "pull", Filter: models.SchemaSubscriptionFilter{Path: "kind", Value: "paid"}},
+	}
+	apply(t, client, desired)
+
+	after, err := client.GetSubscription(ctx, "app", "orders", "billing")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if after.ID != before.ID {
+		t.Errorf("billing was recreated (ID %s, was %s), want it updated in place", after.ID, before.ID)
+	}
+
+	if after.FilterPath != "kind" || after.FilterValue != "paid" {
+		t.Errorf("filter = %s=%s, want kind=paid", after.FilterPath, after.FilterValue)
+	}
+
+	topics, err := client.GetTopics(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(topics) != 1 || topics[0].Slug != "orders" || topics[0].SchemaKey != "key" {
+		t.Errorf("topics = %+v, want only orders with the schema key", topics)
+	}
+}
@@ -0,0 +1,211 @@
+package schema
+
+import (
+	"context"
+
+	"github.com/sailhouse/sailhouse/api"
+	"github.com/sailhouse/sailhouse/models"
+)
+
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionDelete Action = "delete"
+	ActionUpdate Action = "update"
+)
+
+type Kind string
+
+const (
+	KindTopic        Kind = "topic"
+	KindSubscription Kind = "subscription"
+)
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type Change struct {
+	Action Action `json:"action"`
+	Kind   Kind   `json:"kind"`
+	Topic  string `json:"topic"`
+	// Slug of the subscription, empty for topic changes.
+	Slug   string        `json:"slug,omitempty"`
+	Fields []FieldChange `json:"fields,omitempty"`
+
+	// Desired state of the subscription for creates and updates.
+	Subscription *models.SchemaSubscription `json:"-"`
+}
+
+// Name identifies the resource a change applies to, e.g. "orders" or
+// "orders/billing".
+func (c Change) Name() string {
+	if c.Kind == KindSubscription {
+		return c.Topic + "/" + c.Slug
+	}
+
+	return c.Topic
+}
+
+type Plan struct {
+	Key     string   `json:"key,omitempty"`
+	Changes []Change `json:"changes"`
+}
+
+func (p Plan) HasChanges() bool {
+	return len(p.Changes) > 0
+}
+
+// Counts returns the number of resources the plan adds, changes and
+// destroys.
+func (p Plan) Counts() (add, change, destroy int) {
+	for _, c := range p.Changes {
+		switch c.Action {
+		case ActionCreate:
+			add++
+		case ActionUpdate:
+			change++
+		case ActionDelete:
+			destroy++
+		}
+	}
+
+	return add, change, destroy
+}
+
+type DiffOptions struct {
+	// Prune deletes live resources missing from the schema even when they
+	// weren't created with the schema's key.
+	Prune bool
+}
+
+// FetchLive loads every topic in the app along with its subscriptions.
+func FetchLive(ctx context.Context, client *api.SailhouseClient, appID string) (api.GetSchema, error) {
+	live := api.GetSchema{}
+
+	topics, err := client.GetTopics(ctx, appID)
+	if err != nil {
+		return live, err
+	}
+	live.Topics = topics
+
+	for _, topic := range topics {
+		subs, err := client.GetSubscriptions(ctx, appID, topic.Slug)
+		if err != nil {
+			return live, err
+		}
+
+		for _, sub := range subs {
+			if sub.TopicID == "" {
+				sub.TopicID = topic.ID
+			}
+			live.Subscriptions = append(live.Subscriptions, sub)
+		}
+	}
+
+	return live, nil
+}
+
+// Diff computes the changes needed to move the live app to the desired
+// schema. Changes are ordered so they can be applied one after another:
+// topic creates, subscription creates and updates, subscription deletes and
+// finally topic deletes.
+func Diff(desired models.Schema, live api.GetSchema, opts DiffOptions) Plan {
+	plan := Plan{Key: desired.Key, Changes: []Change{}}
+
+	owned := func(key string) bool {
+		return opts.Prune || (desired.Key != "" && key == desired.Key)
+	}
+
+	liveTopics := map[string]models.Topic{}
+	topicSlugsByID := map[string]string{}
+	for _, topic := range live.Topics {
+		liveTopics[topic.Slug] = topic
+		topicSlugsByID[topic.ID] = topic.Slug
+	}
+
+	liveSubs := map[string]models.Subscription{}
+	for _, sub := range live.Subscriptions {
+		liveSubs[topicSlugsByID[sub.TopicID]+"/"+sub.Slug] = sub
+	}
+
+	desiredTopics := map[string]bool{}
+	for _, topic := range desired.Topics {
+		desiredTopics[topic.Slug] = true
+
+		if _, ok := liveTopics[topic.Slug]; !ok {
+			plan.Changes = append(plan.Changes, Change{Action: ActionCreate, Kind: KindTopic, Topic: topic.Slug})
+		}
+	}
+
+	desiredSubs := map[string]bool{}
+	for i := range desired.Subscriptions {
+		sub := desired.Subscriptions[i]
+		key := sub.TopicSlug + "/" + sub.Slug
+		desiredSubs[key] = true
+
+		liveSub, ok := liveSubs[key]
+		if !ok {
+			plan.Changes = append(plan.Changes, Change{
+				Action:       ActionCreate,
+				Kind:         KindSubscription,
+				Topic:        sub.TopicSlug,
+				Slug:         sub.Slug,
+				Fields:       diffSubscription(models.Subscription{}, sub),
+				Subscription: &sub,
+			})
+			continue
+		}
+
+		if fields := diffSubscription(liveSub, sub); len(fields) > 0 {
+			plan.Changes = append(plan.Changes, Change{
+				Action:       ActionUpdate,
+				Kind:         KindSubscription,
+				Topic:        sub.TopicSlug,
+				Slug:         sub.Slug,
+				Fields:       fields,
+				Subscription: &sub,
+			})
+		}
+	}
+
+	for _, sub := range live.Subscriptions {
+		topicSlug := topicSlugsByID[sub.TopicID]
+		// Deleting the topic takes its subscriptions with it.
+		if !desiredTopics[topicSlug] {
+			continue
+		}
+
+		if !desiredSubs[topicSlug+"/"+sub.Slug] && owned(sub.SchemaKey) {
+			plan.Changes = append(plan.Changes, Change{Action: ActionDelete, Kind: KindSubscription, Topic: topicSlug, Slug: sub.Slug})
+		}
+	}
+
+	for _, topic := range live.Topics {
+		if !desiredTopics[topic.Slug] && owned(topic.SchemaKey) {
+			plan.Changes = append(plan.Changes, Change{Action: ActionDelete, Kind: KindTopic, Topic: topic.Slug})
+		}
+	}
+
+	return plan
+}
+
+func diffSubscription(live models.Subscription, desired models.SchemaSubscription) []FieldChange {
+	fields := []FieldChange{}
+
+	compare := func(field, from, to string) {
+		if from != to {
+			fields = append(fields, FieldChange{Field: field, From: from, To: to})
+		}
+	}
+
+	compare("type", live.Type, desired.Type)
+	compare("endpoint", live.Endpoint, desired.Endpoint)
+	compare("filter.path", live.FilterPath, desired.Filter.Path)
+	compare("filter.value", live.FilterValue, desired.Filter.Value)
+
+	return fields
+}
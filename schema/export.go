@@ -0,0 +1,71 @@
+package schema
+
+import (
+	"bytes"
+
+	"github.com/sailhouse/sailhouse/api"
+	"github.com/sailhouse/sailhouse/models"
+	"gopkg.in/yaml.v3"
+)
+
+// FromLive builds a schema describing the live topics and subscriptions of an
+// app, in the same order they were returned by the API.
+func FromLive(live api.GetSchema, key string) models.Schema {
+	s := models.Schema{
+		Key:           key,
+		Topics:        []models.SchemaTopic{},
+		Subscriptions: []models.SchemaSubscription{},
+	}
+
+	topicSlugsByID := map[string]string{}
+	for _, topic := range live.Topics {
+		topicSlugsByID[topic.ID] = topic.Slug
+		s.Topics = append(s.Topics, models.SchemaTopic{Slug: topic.Slug})
+	}
+
+	for _, sub := range live.Subscriptions {
+		s.Subscriptions = append(s.Subscriptions, models.SchemaSubscription{
+			Slug:      sub.Slug,
+			TopicSlug: topicSlugsByID[sub.TopicID],
+			Type:      sub.Type,
+			Endpoint:  sub.Endpoint,
+			Filter: models.SchemaSubscriptionFilter{
+				Path:  sub.FilterPath,
+				Value: sub.FilterValue,
+			},
+		})
+	}
+
+	return s
+}
+
+// SharedKey returns the schema key every live topic was created with, or an
+// empty string if they don't all share one.
+func SharedKey(live api.GetSchema) string {
+	key := ""
+	for i, topic := range live.Topics {
+		if i > 0 && topic.SchemaKey != key {
+			return ""
+		}
+		key = topic.SchemaKey
+	}
+
+	return key
+}
+
+func Marshal(s models.Schema) ([]byte, error) {
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(s); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
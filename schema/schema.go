@@ -0,0 +1,136 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sailhouse/sailhouse/models"
+	"github.com/sailhouse/sailhouse/util"
+	"gopkg.in/yaml.v3"
+)
+
+func Load(path string) (models.Schema, error) {
+	var s models.Schema
+
+	schemaBytes, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(schemaBytes))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&s); err != nil {
+		return s, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("yaml"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	v.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+		return util.IsValidSlug(fl.Field().String())
+	})
+
+	v.RegisterValidation("endpoint", func(fl validator.FieldLevel) bool {
+		endpoint := fl.Field().String()
+		return endpoint == "" || util.IsValidEndpoint(endpoint)
+	})
+
+	return v
+}
+
+// Validate checks the field constraints declared on models.Schema as well as
+// the references between topics and subscriptions.
+func Validate(s models.Schema) error {
+	problems := []string{}
+
+	if err := newValidator().Struct(s); err != nil {
+		var validationErrors validator.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			return err
+		}
+
+		for _, fieldErr := range validationErrors {
+			problems = append(problems, describeFieldError(fieldErr))
+		}
+	}
+
+	topics := map[string]bool{}
+	for _, topic := range s.Topics {
+		if topics[topic.Slug] {
+			problems = append(problems, fmt.Sprintf("topic %s is declared more than once", topic.Slug))
+		}
+		topics[topic.Slug] = true
+	}
+
+	subscriptions := map[string]bool{}
+	for _, sub := range s.Subscriptions {
+		if !topics[sub.TopicSlug] {
+			problems = append(problems, fmt.Sprintf("subscription %s references undeclared topic %s", sub.Slug, sub.TopicSlug))
+		}
+
+		if sub.Type == "pull" && sub.Endpoint != "" {
+			problems = append(problems, fmt.Sprintf("subscription %s is a pull subscription and cannot have an endpoint", sub.Slug))
+		}
+
+		key := sub.TopicSlug + "/" + sub.Slug
+		if subscriptions[key] {
+			problems = append(problems, fmt.Sprintf("subscription %s is declared more than once on topic %s", sub.Slug, sub.TopicSlug))
+		}
+		subscriptions[key] = true
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid schema:\n - %s", strings.Join(e.Problems, "\n - "))
+}
+
+func describeFieldError(err validator.FieldError) string {
+	// Namespace looks like "Schema.subscriptions[0].endpoint", drop the
+	// struct name so it reads like a path into the YAML document.
+	field := err.Namespace()
+	if i := strings.Index(field, "."); i >= 0 {
+		field = field[i+1:]
+	}
+
+	switch err.Tag() {
+	case "required", "required_if", "required_with":
+		return fmt.Sprintf("%s is required", field)
+	case "slug":
+		return fmt.Sprintf("%s must only contain lowercase letters, numbers or dashes", field)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, err.Param())
+	case "endpoint":
+		return fmt.Sprintf("%s must be a valid HTTPS URL", field)
+	case "max":
+		return fmt.Sprintf("%s must be at most %s characters", field, err.Param())
+	default:
+		return fmt.Sprintf("%s failed the %s check", field, err.Tag())
+	}
+}
@@ -17,3 +17,14 @@ func IsValidEndpoint(endpoint string) bool {
 
 	return true
 }
+
+// IsValidForwardEndpoint is like IsValidEndpoint but also accepts plain HTTP,
+// since events forwarded by the CLI never leave the developer's machine.
+func IsValidForwardEndpoint(endpoint string) bool {
+	u, err := url.ParseRequestURI(endpoint)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
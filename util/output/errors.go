@@ -0,0 +1,115 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Exit codes, one per class of error so scripts can tell "topic missing"
+// from "auth expired":
+//
+//	0  success
+//	1  any other error
+//	2  drift reported by `sailhouse plan`
+//	3  validation, the request or input was invalid, or input was needed
+//	   but prompting was disabled
+//	4  unauthorized, the token is missing, expired or lacks access
+//	5  not found
+//	6  conflict, e.g. the resource already exists
+//	7  rate limited
+//	8  network, the API couldn't be reached
+//	9  server, the API failed
+const (
+	ExitOK           = 0
+	ExitError        = 1
+	ExitDrift        = 2
+	ExitValidation   = 3
+	ExitUnauthorized = 4
+	ExitNotFound     = 5
+	ExitConflict     = 6
+	ExitRateLimited  = 7
+	ExitNetwork      = 8
+	ExitServer       = 9
+)
+
+const (
+	CodeError        = "error"
+	CodeNotFound     = "not_found"
+	CodeConflict     = "conflict"
+	CodeValidation   = "validation"
+	CodeUnauthorized = "unauthorized"
+)
+
+var exitCodes = map[string]int{
+	"validation":   ExitValidation,
+	"no_input":     ExitValidation,
+	"unauthorized": ExitUnauthorized,
+	"not_found":    ExitNotFound,
+	"conflict":     ExitConflict,
+	"rate_limited": ExitRateLimited,
+	"network":      ExitNetwork,
+	"server":       ExitServer,
+}
+
+// CodedError is implemented by errors that carry a machine-readable code,
+// such as api.Error.
+type CodedError interface {
+	error
+	ErrorCode() string
+	ErrorDetails() any
+}
+
+// WithCode attaches a code to an error that doesn't carry one.
+func WithCode(code string, err error) error {
+	return &codeError{code: code, err: err}
+}
+
+type codeError struct {
+	code string
+	err  error
+}
+
+func (e *codeError) Error() string     { return e.err.Error() }
+func (e *codeError) Unwrap() error     { return e.err }
+func (e *codeError) ErrorCode() string { return e.code }
+func (e *codeError) ErrorDetails() any { return nil }
+
+// Error is how errors are reported, as JSON under `--format json`.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+func (e Error) ExitCode() int {
+	if code, ok := exitCodes[e.Code]; ok {
+		return code
+	}
+
+	return ExitError
+}
+
+func NewError(message string, err ...error) Error {
+	e := Error{Code: CodeError, Message: message}
+	if len(err) == 0 || err[0] == nil {
+		return e
+	}
+
+	// Coded errors describe themselves, without the wrapping added by the
+	// HTTP client.
+	cause := err[0]
+	var coded CodedError
+	if errors.As(cause, &coded) {
+		e.Code = coded.ErrorCode()
+		e.Details = coded.ErrorDetails()
+		cause = coded
+	}
+
+	if message == "" {
+		e.Message = cause.Error()
+	} else {
+		e.Message = fmt.Sprintf("%s: %s", message, cause.Error())
+	}
+
+	return e
+}
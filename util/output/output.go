@@ -3,6 +3,7 @@ package output
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
@@ -15,7 +16,7 @@ var (
 
 type Output[T any] struct {
 	Messages []string
-	Errors   []string
+	Errors   []Error
 	Table    *Table
 	// Used for non-text output
 	Data T
@@ -27,6 +28,10 @@ func WithOutput[T any](f func(cmd *cobra.Command, args []string, output *Output[
 		f(cmd, args, output)
 
 		output.Print()
+
+		if len(output.Errors) > 0 {
+			os.Exit(output.ExitCode())
+		}
 	}
 }
 
@@ -38,12 +43,26 @@ func (o *Output[T]) AddMessage(message string) {
 	o.Messages = append(o.Messages, message)
 }
 
+// AddError records an error. When err carries a code, e.g. an api.Error,
+// the code decides the exit status and is included in JSON output.
 func (o *Output[T]) AddError(message string, err ...error) {
-	if len(err) > 0 {
-		message = fmt.Sprintf("%s: %s", message, err[0].Error())
+	o.Errors = append(o.Errors, NewError(message, err...))
+}
+
+// AddCodedError records an error found by the CLI itself, e.g. a missing
+// topic, with the code it should be reported under.
+func (o *Output[T]) AddCodedError(code, message string) {
+	o.Errors = append(o.Errors, Error{Code: code, Message: message})
+}
+
+// ExitCode is the status the command should exit with, taken from the first
+// error.
+func (o *Output[T]) ExitCode() int {
+	if len(o.Errors) == 0 {
+		return ExitOK
 	}
 
-	o.Errors = append(o.Errors, message)
+	return o.Errors[0].ExitCode()
 }
 
 func (o *Output[T]) SetData(data T) {
@@ -61,7 +80,7 @@ func (o *Output[T]) Print() {
 	case "json":
 		o.PrintJSON()
 	default:
-		if o.Table != nil {
+		if o.Table != nil && len(o.Errors) == 0 {
 			o.Table.Print()
 		} else {
 			o.PrintText()
@@ -73,13 +92,13 @@ func (o *Output[T]) Print() {
 
 func (o *Output[T]) PrintErrors() {
 	if len(o.Errors) == 1 {
-		fmt.Println(textErr.Render(o.Errors[0]))
+		fmt.Println(textErr.Render(o.Errors[0].Message))
 		return
 	}
 
 	fmt.Println(textErr.Render("Errors:"))
 	for _, err := range o.Errors {
-		fmt.Printf(" - %s\n", textErr.Render(err))
+		fmt.Printf(" - %s\n", textErr.Render(err.Message))
 	}
 }
 
@@ -0,0 +1,72 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+// Stream prints records as they arrive instead of once the command
+// finishes. Under `--format json` each record is a single JSON line.
+type Stream[T any] struct {
+	exitCode int
+}
+
+func NewStream[T any]() *Stream[T] {
+	return &Stream[T]{}
+}
+
+func (s *Stream[T]) Write(data T, text string) {
+	if viper.Get("format") != "json" {
+		fmt.Println(text)
+		return
+	}
+
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		fmt.Printf("{\"errors\": [%q]}\n", err.Error())
+		return
+	}
+
+	fmt.Printf("%s\n", dataBytes)
+}
+
+func (s *Stream[T]) Message(message string) {
+	if viper.Get("format") == "json" {
+		return
+	}
+
+	fmt.Println(message)
+}
+
+func (s *Stream[T]) Error(message string, err ...error) {
+	e := NewError(message, err...)
+	if s.exitCode == ExitOK {
+		s.exitCode = e.ExitCode()
+	}
+
+	if viper.Get("format") == "json" {
+		errBytes, _ := json.Marshal([]Error{e})
+		fmt.Printf("{\"errors\": %s}\n", errBytes)
+		return
+	}
+
+	fmt.Println(textErr.Render(e.Message))
+}
+
+// ExitOnError exits with the stream's exit code when an error was written.
+// It's meant to be deferred straight after creating the stream so it runs
+// after the command's other deferred cleanup.
+func (s *Stream[T]) ExitOnError() {
+	if s.exitCode != ExitOK {
+		os.Exit(s.exitCode)
+	}
+}
+
+// ExitCode is the status the command should exit with, taken from the first
+// error written.
+func (s *Stream[T]) ExitCode() int {
+	return s.exitCode
+}
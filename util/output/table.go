@@ -8,6 +8,7 @@ import (
 type Table struct {
 	columns []string
 	rows    [][]string
+	footer  string
 }
 
 func NewTable() *Table {
@@ -31,6 +32,12 @@ func (t *Table) AddRow(row ...string) error {
 	return nil
 }
 
+// SetFooter sets a line printed below the rows, e.g. to say the table is
+// incomplete.
+func (t *Table) SetFooter(footer string) {
+	t.footer = footer
+}
+
 func (t *Table) Print() {
 	widths := make([]int, len(t.columns))
 
@@ -66,5 +73,10 @@ func (t *Table) Print() {
 		fmt.Println()
 	}
 
+	if t.footer != "" {
+		fmt.Println()
+		fmt.Println(t.footer)
+	}
+
 	fmt.Println()
 }
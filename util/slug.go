@@ -0,0 +1,11 @@
+package util
+
+import "regexp"
+
+var slugRegex = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// IsValidSlug reports whether s can be used as a team, app, topic or
+// subscription slug.
+func IsValidSlug(s string) bool {
+	return slugRegex.MatchString(s)
+}